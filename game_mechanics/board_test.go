@@ -1,6 +1,7 @@
 package gamemechanics
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -22,12 +23,15 @@ func (factory PredictableVarianceFactory) GenerateDeflectionSource(gameBoard Gam
 func TestNewGameBoard(t *testing.T) {
 
 	processedGameBoard, err := NewGameBoard(GameBoardDefenition{
-		PlayerIds: []string{"red", "blue"},
-		YMax:      2,
-		XMax:      4,
+		PlayerIds:   []string{"red", "blue"},
+		YMax:        2,
+		XMax:        4,
+		TargetScore: 6,
 		Events: []GameEvent{
 			NewCreatePawnEvent(position(2, 1), "red"),
+			NewEndTurnEvent("red"),
 			NewCreatePawnEvent(position(3, 2), "blue"),
+			NewEndTurnEvent("blue"),
 		},
 	})
 	if err != nil || len(processedGameBoard.GameBoard.Pawns) < 2 || len(processedGameBoard.GameBoard.Pawns[1]) < 4 {
@@ -57,15 +61,21 @@ func TestNewGameBoard(t *testing.T) {
 
 func TestPawnTraversal(t *testing.T) {
 	processedGameBoard, err := NewGameBoard(GameBoardDefenition{
-		PlayerIds: []string{"red", "blue"},
-		YMax:      2,
-		XMax:      4,
+		PlayerIds:   []string{"red", "blue"},
+		YMax:        2,
+		XMax:        4,
+		TargetScore: 6,
 		Events: []GameEvent{
 			NewCreatePawnEvent(position(2, 2), "red"),
+			NewEndTurnEvent("red"),
 			NewCreatePawnEvent(position(2, 1), "blue"),
+			NewEndTurnEvent("blue"),
 			NewCreatePawnEvent(position(2, 0), "red"),
+			NewEndTurnEvent("red"),
 			NewCreatePawnEvent(position(0, 1), "blue"),
+			NewEndTurnEvent("blue"),
 			NewCreatePawnEvent(position(4, 1), "red"),
+			NewEndTurnEvent("red"),
 		},
 	})
 
@@ -137,17 +147,24 @@ func TestPawnTraversal(t *testing.T) {
 
 func TestGetFinalDirection(t *testing.T) {
 	processedGameBoard, err := newGameBoard(GameBoardDefenition{
-		Id:        "-",
-		PlayerIds: []string{"red", "blue"},
-		YMax:      2,
-		XMax:      4,
+		Id:          "-",
+		PlayerIds:   []string{"red", "blue"},
+		YMax:        2,
+		XMax:        4,
+		TargetScore: 6,
 		Events: []GameEvent{
 			NewCreatePawnEvent(position(2, 0), "red"),
+			NewEndTurnEvent("red"),
 			NewCreatePawnEvent(position(1, 0), "blue"),
+			NewEndTurnEvent("blue"),
 			NewCreatePawnEvent(position(1, 2), "red"),
+			NewEndTurnEvent("red"),
 			NewCreatePawnEvent(position(3, 2), "blue"),
+			NewEndTurnEvent("blue"),
 			NewCreatePawnEvent(position(3, 0), "red"),
+			NewEndTurnEvent("red"),
 			NewCreatePawnEvent(position(2, 1), "blue"),
+			NewEndTurnEvent("blue"),
 			NewFireDeflectorEvent(),
 		},
 	}, PredictableVarianceFactory{
@@ -166,3 +183,370 @@ func TestGetFinalDirection(t *testing.T) {
 		t.Errorf("Wrong final direction %d", deflections[len(deflections)-1].ToDirection)
 	}
 }
+
+func TestBlockerAbsorbsDeflector(t *testing.T) {
+	processedGameBoard, err := newGameBoard(GameBoardDefenition{
+		Id:             "-",
+		PlayerIds:      []string{"red", "blue"},
+		YMax:           2,
+		XMax:           4,
+		TargetScore:    6,
+		PawnDurability: 5,
+		Events: []GameEvent{
+			NewCreatePawnEvent(position(2, 1), "red"),
+			NewFireDeflectorEvent(),
+		},
+	}, PredictableVarianceFactory{
+		variants: map[string][]string{
+			"-red":  {BLOCKER, SLASH},
+			"-blue": {SLASH},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create game board: %v", err)
+	}
+
+	deflections := processedGameBoard.LastDeflections
+	if len(deflections) != 2 {
+		t.Fatalf("Expected the deflector to stop at the blocker, got %d deflections", len(deflections))
+	}
+	blocked := deflections[1]
+	if blocked.Position != position(2, 1) || blocked.ToDirection != BLOCKED {
+		t.Errorf("Wrong blocked deflection %v", blocked)
+	}
+	if len(blocked.Events) != 1 || blocked.Events[0].Durability != 4 {
+		t.Errorf("The blocker did not lose durability %v", blocked.Events)
+	}
+
+	scoreBoard := processedGameBoard.GameBoard.ScoreBoard
+	if scoreBoard["red"] != 0 || scoreBoard["blue"] != 0 {
+		t.Errorf("Somebody scored from a blocked deflector %v", scoreBoard)
+	}
+}
+
+func TestSplitterBranchesTheDeflector(t *testing.T) {
+	processedGameBoard, err := newGameBoard(GameBoardDefenition{
+		Id:             "-",
+		PlayerIds:      []string{"red", "blue"},
+		YMax:           2,
+		XMax:           4,
+		TargetScore:    6,
+		PawnDurability: 5,
+		Events: []GameEvent{
+			NewCreatePawnEvent(position(2, 1), "red"),
+			NewFireDeflectorEvent(),
+		},
+	}, PredictableVarianceFactory{
+		variants: map[string][]string{
+			"-red":  {SPLITTER, SLASH},
+			"-blue": {SLASH},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create game board: %v", err)
+	}
+
+	deflections := processedGameBoard.LastDeflections
+	if len(deflections) != 3 {
+		t.Fatalf("Expected the deflector to go through the splitter, got %d deflections", len(deflections))
+	}
+	split := deflections[1]
+	if split.Position != position(2, 1) || split.ToDirection != RIGHT || len(split.Branches) != 1 {
+		t.Fatalf("Wrong split deflection %v", split)
+	}
+	if len(split.Events) != 1 || split.Events[0].Durability != 4 {
+		t.Errorf("The splitter did not lose durability once %v", split.Events)
+	}
+
+	branch := split.Branches[0]
+	if len(branch) != 2 || branch[0].Position != position(2, 1) || branch[1].Position != position(-1, 1) || branch[1].ToDirection != LEFT {
+		t.Errorf("Wrong branch %v", branch)
+	}
+
+	if !reflect.DeepEqual(getExitDirections(deflections), []int{LEFT, RIGHT}) {
+		t.Errorf("Wrong exit directions %v", getExitDirections(deflections))
+	}
+
+	// both edges were reached by the same fire
+	scoreBoard := processedGameBoard.GameBoard.ScoreBoard
+	if scoreBoard["red"] != 1 || scoreBoard["blue"] != 1 {
+		t.Errorf("Expected both players to score %v", scoreBoard)
+	}
+
+	branches := split.toMap()["branches"].([][]map[string]interface{})
+	if len(branches) != 1 || len(branches[0]) != 2 {
+		t.Errorf("The branch is missing from the map %v", branches)
+	}
+}
+
+func newPortalGameBoard(t *testing.T, redVariants []string) ProcessedGameBoard {
+	processedGameBoard, err := newGameBoard(GameBoardDefenition{
+		Id:             "-",
+		PlayerIds:      []string{"red", "blue"},
+		YMax:           4,
+		XMax:           4,
+		TargetScore:    6,
+		PawnDurability: 5,
+		UndosPerTurn:   1,
+	}, PredictableVarianceFactory{
+		variants: map[string][]string{
+			"-red":  redVariants,
+			"-blue": {SLASH},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create game board: %v", err)
+	}
+	processedGameBoard.GameBoard.ScoreBoard["red"] = 3
+	return processedGameBoard
+}
+
+func TestPortalTeleportsTheDeflector(t *testing.T) {
+	processedGameBoard, err := ProcessEvents(newPortalGameBoard(t, []string{PORTAL, PORTAL, SLASH, SLASH}), []GameEvent{
+		NewCreatePawnEvent(position(2, 1), "red"),
+		NewCreateLinkedPawnEvent(position(4, 3), position(2, 1), "red"),
+		NewCreatePawnEvent(position(4, 4), "red"),
+		NewFireDeflectorEvent(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to process events: %v", err)
+	}
+
+	entry, _ := processedGameBoard.GameBoard.GetPawn(position(2, 1))
+	exit, _ := processedGameBoard.GameBoard.GetPawn(position(4, 3))
+	if entry.Twin == nil || *entry.Twin != position(4, 3) || exit.Twin == nil || *exit.Twin != position(2, 1) {
+		t.Fatalf("The portals were not linked to each other")
+	}
+
+	deflections := processedGameBoard.LastDeflections
+	if len(deflections) != 5 {
+		t.Fatalf("Expected the deflector to go through the portals, got %d deflections", len(deflections))
+	}
+	if deflections[1].Position != position(2, 1) || deflections[1].ToDirection != UP || deflections[1].Teleport {
+		t.Errorf("Wrong portal entry %v", deflections[1])
+	}
+	if deflections[2].Position != position(4, 3) || deflections[2].ToDirection != UP || !deflections[2].Teleport {
+		t.Errorf("Wrong portal exit %v", deflections[2])
+	}
+	if entry.Durability != 4 || exit.Durability != 5 {
+		t.Errorf("Only the entered portal should lose durability, got %d and %d", entry.Durability, exit.Durability)
+	}
+	if deflections[4].Position != position(5, 4) || processedGameBoard.GameBoard.ScoreBoard["blue"] != 1 {
+		t.Errorf("The deflector did not exit from the edge of blue %v", deflections[4])
+	}
+
+	// the link is kept in the snapshots
+	if restored := fromSnapshotPawn(toSnapshotPawn(*entry)); !reflect.DeepEqual(restored, *entry) {
+		t.Errorf("Restored the portal %v as %v", *entry, restored)
+	}
+}
+
+func TestLinkingPortals(t *testing.T) {
+	invalidLinks := map[string][]GameEvent{
+		"not a portal": {
+			NewCreatePawnEvent(position(2, 1), "red"),
+			NewCreateLinkedPawnEvent(position(4, 3), position(2, 1), "red"),
+		},
+		"itself": {
+			NewCreateLinkedPawnEvent(position(2, 1), position(2, 1), "red"),
+		},
+		"no portal": {
+			NewCreateLinkedPawnEvent(position(2, 1), position(0, 0), "red"),
+		},
+	}
+	for name, events := range invalidLinks {
+		variants := []string{PORTAL, SLASH, SLASH}
+		if name == "not a portal" {
+			variants = []string{SLASH, PORTAL, SLASH}
+		}
+		if _, err := ProcessEvents(newPortalGameBoard(t, variants), events); err == nil {
+			t.Errorf("Linked a portal to %s", name)
+		}
+	}
+
+	processedGameBoard, err := ProcessEvents(newPortalGameBoard(t, []string{PORTAL, PORTAL, PORTAL, SLASH}), []GameEvent{
+		NewCreatePawnEvent(position(2, 1), "red"),
+		NewCreateLinkedPawnEvent(position(4, 3), position(2, 1), "red"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to link portals: %v", err)
+	}
+	if _, err := ProcessEvents(processedGameBoard.clone(), []GameEvent{NewCreateLinkedPawnEvent(position(0, 0), position(2, 1), "red")}); err == nil {
+		t.Errorf("Linked a portal that already has a twin")
+	}
+
+	// taking back the second portal leaves the first one without a twin
+	processedGameBoard, err = ProcessEvents(processedGameBoard, []GameEvent{NewUndoPawnEvent("red")})
+	if err != nil {
+		t.Fatalf("Failed to undo: %v", err)
+	}
+	if pawn, _ := processedGameBoard.GameBoard.GetPawn(position(2, 1)); pawn.Twin != nil {
+		t.Errorf("The portal is still linked to the portal that was taken back")
+	}
+}
+
+func TestPortalLoopIsAbsorbed(t *testing.T) {
+	processedGameBoard, err := ProcessEvents(newPortalGameBoard(t, []string{PORTAL, PORTAL, SLASH}), []GameEvent{
+		NewCreatePawnEvent(position(2, 1), "red"),
+		NewCreateLinkedPawnEvent(position(4, 3), position(2, 1), "red"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to link portals: %v", err)
+	}
+
+	// the deflector already went up through the portal in this fire
+	teleports := map[DirectedPosition]bool{
+		{Position: position(2, 1), Direction: UP}: true,
+	}
+	gameBoard, deflections := processBeam(processedGameBoard.GameBoard, DirectedPosition{Position: position(2, -1), Direction: UP}, teleports)
+
+	if len(deflections) != 2 || deflections[1].Position != position(2, 1) || deflections[1].ToDirection != BLOCKED {
+		t.Errorf("Expected the deflector to be absorbed by the portal %v", deflections)
+	}
+	if gameBoard.ScoreBoard["red"] != 1 || gameBoard.ScoreBoard["blue"] != 0 {
+		t.Errorf("Somebody scored from a looping deflector %v", gameBoard.ScoreBoard)
+	}
+}
+
+func TestEachPlayerOwnsAnEdge(t *testing.T) {
+	playerIds := []string{"red", "blue", "green", "yellow"}
+	directions := []int{LEFT, RIGHT, UP, DOWN}
+
+	for playerCount := MIN_PLAYERS; playerCount <= MAX_PLAYERS; playerCount++ {
+		defenition := GameBoardDefenition{PlayerIds: playerIds[:playerCount]}
+		for i, direction := range directions {
+			playerId, ok := GetPlayerFromDirection(defenition, direction)
+			if i < playerCount && (!ok || playerId != playerIds[i]) {
+				t.Errorf("Expected %s to own the edge %d in a %d player game, got %s", playerIds[i], direction, playerCount, playerId)
+			}
+			if i >= playerCount && ok {
+				t.Errorf("The edge %d has an owner in a %d player game", direction, playerCount)
+			}
+		}
+	}
+}
+
+func TestDeflectionScoresForTheEdgeOwner(t *testing.T) {
+	processedGameBoard, err := newGameBoard(GameBoardDefenition{
+		PlayerIds:   []string{"red", "blue", "green", "yellow"},
+		YMax:        2,
+		XMax:        2,
+		TargetScore: 6,
+		Events:      []GameEvent{NewFireDeflectorEvent()},
+	}, PredictableVarianceFactory{
+		variants: map[string][]string{
+			"red":    {SLASH},
+			"blue":   {SLASH},
+			"green":  {SLASH},
+			"yellow": {SLASH},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create board: %v", err)
+	}
+
+	// the deflector goes up through the empty board, into the edge of the third player
+	if processedGameBoard.GameBoard.ScoreBoard["green"] != 1 {
+		t.Errorf("Expected green to score, the score board is %v", processedGameBoard.GameBoard.ScoreBoard)
+	}
+}
+
+func TestEliminatedPlayerEdgeIsNeutral(t *testing.T) {
+	processedGameBoard, err := newGameBoard(GameBoardDefenition{
+		PlayerIds:   []string{"red", "blue", "green", "yellow"},
+		YMax:        2,
+		XMax:        2,
+		TargetScore: 6,
+		Events: []GameEvent{
+			NewEliminatePlayerEvent("green", END_REASON_RESIGN),
+			NewFireDeflectorEvent(),
+		},
+	}, PredictableVarianceFactory{
+		variants: map[string][]string{
+			"red":    {SLASH},
+			"blue":   {SLASH},
+			"green":  {SLASH},
+			"yellow": {SLASH},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create board: %v", err)
+	}
+
+	// the deflector still exits from the edge of green, but green is out of the game
+	deflections := processedGameBoard.LastDeflections
+	if deflections[len(deflections)-1].ToDirection != UP {
+		t.Fatalf("Expected the deflector to exit up, got %v", deflections)
+	}
+	if processedGameBoard.GameBoard.ScoreBoard["green"] != 0 {
+		t.Errorf("The eliminated player scored, the score board is %v", processedGameBoard.GameBoard.ScoreBoard)
+	}
+}
+
+func TestBranchCanReuseThePortalOfTheTrunk(t *testing.T) {
+	processedGameBoard, err := newGameBoard(GameBoardDefenition{
+		Id:          "-",
+		PlayerIds:   []string{"red", "blue"},
+		YMax:        4,
+		XMax:        4,
+		TargetScore: 6,
+	}, PredictableVarianceFactory{
+		variants: map[string][]string{
+			"-red":  {SLASH},
+			"-blue": {SLASH},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create game board: %v", err)
+	}
+
+	twinOfEntry := position(0, 4)
+	twinOfExit := position(4, 1)
+	pawns := []Pawn{
+		{Position: position(2, 1), Name: SPLITTER},
+		{Position: position(0, 1), Name: BACKSLASH},
+		{Position: position(0, 3), Name: SLASH},
+		{Position: position(2, 3), Name: BACKSLASH},
+		{Position: position(4, 1), Name: PORTAL, Twin: &twinOfEntry},
+		{Position: position(0, 4), Name: PORTAL, Twin: &twinOfExit},
+	}
+	gameBoard := processedGameBoard.GameBoard
+	for _, pawn := range pawns {
+		pawn.Durability = 2
+		pawn.PlayerOwner = "red"
+		if gameBoard.Pawns, err = addPawn(gameBoard.Pawns, pawn); err != nil {
+			t.Fatalf("Failed to add pawn: %v", err)
+		}
+	}
+
+	// the branch goes around the board, back through the splitter and into the portal on the right,
+	// then the trunk goes right into the same portal without being in a loop
+	_, deflections := ProcessDeflection(gameBoard, DirectedPosition{Position: position(2, -1), Direction: UP})
+
+	if len(deflections) != 5 {
+		t.Fatalf("Expected the trunk to go through the portal, got %v", deflections)
+	}
+	if !deflections[3].Teleport || deflections[3].Position != position(0, 4) {
+		t.Errorf("Expected the trunk to come out of the twin portal, got %v", deflections[3])
+	}
+	if deflections[4].ToDirection != RIGHT {
+		t.Errorf("Expected the trunk to exit right, got %v", deflections[4])
+	}
+
+	branchTeleports := 0
+	var countTeleports func(deflections []Deflection)
+	countTeleports = func(deflections []Deflection) {
+		for _, deflection := range deflections {
+			if deflection.Teleport {
+				branchTeleports += 1
+			}
+			for _, branch := range deflection.Branches {
+				countTeleports(branch)
+			}
+		}
+	}
+	countTeleports(deflections[1].Branches[0])
+	if branchTeleports != 1 {
+		t.Errorf("Expected the branch to go through the portal once, got %d", branchTeleports)
+	}
+}
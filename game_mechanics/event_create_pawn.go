@@ -6,6 +6,8 @@ type CreatePawnEvent struct {
 	name        string
 	position    Position
 	playerOwner string
+	// twin is the portal that a new portal is linked to
+	twin *Position
 }
 
 func NewCreatePawnEvent(pos Position, playerOwner string) CreatePawnEvent {
@@ -16,13 +18,21 @@ func NewCreatePawnEvent(pos Position, playerOwner string) CreatePawnEvent {
 	}
 }
 
+// NewCreateLinkedPawnEvent places a portal and links it to the portal of the player at the twin position
+func NewCreateLinkedPawnEvent(pos Position, twin Position, playerOwner string) CreatePawnEvent {
+	event := NewCreatePawnEvent(pos, playerOwner)
+	event.twin = &twin
+	return event
+}
+
 func (event CreatePawnEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoard) (ProcessedGameBoard, error) {
 	currentPlayer := GetPlayerTurn(gameBoardInProcess.GameBoard)
 	if event.playerOwner != currentPlayer {
 		return ProcessedGameBoard{}, errors.New("out of turn action")
 	}
 
-	if gameBoardInProcess.GameBoard.ScoreBoard[event.playerOwner] <= 0 {
+	side := getPlayerSide(gameBoardInProcess.GameBoard.defenition, event.playerOwner)
+	if gameBoardInProcess.GameBoard.ScoreBoard[side] <= 0 {
 		return ProcessedGameBoard{}, errors.New("out of score")
 	}
 
@@ -33,9 +43,24 @@ func (event CreatePawnEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoa
 		Position:    event.position,
 		Name:        variant,
 		TurnPlaced:  gameBoardInProcess.GameBoard.Turn,
-		Durability:  5,
+		Durability:  gameBoardInProcess.GameBoard.defenition.PawnDurability,
 		PlayerOwner: event.playerOwner,
 	}
+
+	if event.twin != nil {
+		if err := checkTwin(gameBoardInProcess.GameBoard, newPawn, *event.twin); err != nil {
+			return ProcessedGameBoard{}, err
+		}
+		twin := *event.twin
+		newPawn.Twin = &twin
+	}
+
+	var replacedPawn *Pawn
+	if pawn, err := gameBoardInProcess.GameBoard.GetPawn(event.position); err == nil {
+		replacedPawnCopy := *pawn
+		replacedPawn = &replacedPawnCopy
+	}
+
 	gameBoardInProcess.PawnVariants[event.playerOwner] = gameBoardInProcess.VarianceFactory.GeneratePawnVariant(getPlayerDigest(gameBoardInProcess.GameBoard.defenition, event.playerOwner), len(variants)+1)
 	updatedPawns, err := addPawn(gameBoardInProcess.GameBoard.Pawns, newPawn)
 	if err != nil {
@@ -43,24 +68,84 @@ func (event CreatePawnEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoa
 	}
 	gameBoardInProcess.GameBoard.Pawns = updatedPawns
 
-	gameBoardInProcess.GameBoard.ScoreBoard[event.playerOwner] -= 1
+	gameBoardInProcess.GameBoard.ScoreBoard[side] -= 1
+	gameBoardInProcess.UndoablePlacements = append(gameBoardInProcess.UndoablePlacements, PawnPlacement{
+		Position:     event.position,
+		ReplacedPawn: replacedPawn,
+	})
 
 	return gameBoardInProcess, nil
 }
 
+// checkTwin only links a new portal to an unlinked portal of the same player
+func checkTwin(gameBoard GameBoard, newPawn Pawn, twinPosition Position) error {
+	if newPawn.Name != PORTAL {
+		return errors.New("only a portal can be linked")
+	}
+	if twinPosition == newPawn.Position {
+		return errors.New("a portal cannot be linked to itself")
+	}
+
+	twin, err := gameBoard.GetPawn(twinPosition)
+	if err != nil || twin == nil || twin.Name != PORTAL || twin.PlayerOwner != newPawn.PlayerOwner {
+		return errors.New("a portal can only be linked to a portal of the same player")
+	}
+	if twin.Twin != nil {
+		return errors.New("the portal is already linked")
+	}
+	return nil
+}
+
 func (event CreatePawnEvent) Encode() map[string]interface{} {
-	return map[string]interface{}{
+	props := map[string]interface{}{
 		"name":         event.name,
 		"position_x":   event.position.X,
 		"position_y":   event.position.Y,
 		"player_owner": event.playerOwner,
+		"links_twin":   event.twin != nil,
+	}
+	if event.twin != nil {
+		props["twin_x"] = event.twin.X
+		props["twin_y"] = event.twin.Y
 	}
+	return props
 }
 
-func (event CreatePawnEvent) Decode(anyMap map[string]interface{}) GameEvent {
-	event.name = anyMap["name"].(string)
-	event.playerOwner = anyMap["player_owner"].(string)
-	event.position = position(int(anyMap["position_x"].(int32)), int(anyMap["position_y"].(int32)))
+func (event CreatePawnEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
 
-	return event
+	x, err := getInt(anyMap, "position_x")
+	if err != nil {
+		return nil, err
+	}
+	y, err := getInt(anyMap, "position_y")
+	if err != nil {
+		return nil, err
+	}
+	event.position = position(x, y)
+
+	linksTwin, err := getBool(anyMap, "links_twin")
+	if err != nil {
+		return nil, err
+	}
+	if linksTwin {
+		twinX, err := getInt(anyMap, "twin_x")
+		if err != nil {
+			return nil, err
+		}
+		twinY, err := getInt(anyMap, "twin_y")
+		if err != nil {
+			return nil, err
+		}
+		twin := position(twinX, twinY)
+		event.twin = &twin
+	}
+
+	return event, nil
 }
@@ -0,0 +1,106 @@
+package gamemechanics
+
+import "errors"
+
+// OfferDrawEvent stays pending until the opponent accepts it,
+// or until the turn of the player who offered it comes back
+type OfferDrawEvent struct {
+	name        string
+	playerOwner string
+}
+
+func NewOfferDrawEvent(playerOwner string) OfferDrawEvent {
+	return OfferDrawEvent{
+		name:        OFFER_DRAW,
+		playerOwner: playerOwner,
+	}
+}
+
+func (event OfferDrawEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoard) (ProcessedGameBoard, error) {
+	if !gameBoardInProcess.GameInProgress {
+		return ProcessedGameBoard{}, errors.New("the game is already over")
+	}
+	if !isPlayer(gameBoardInProcess.GameBoard.defenition, event.playerOwner) || gameBoardInProcess.EliminatedPlayers[event.playerOwner] {
+		return ProcessedGameBoard{}, errors.New("only a player of the game can offer a draw")
+	}
+	if len(getActiveSides(gameBoardInProcess)) > 2 {
+		return ProcessedGameBoard{}, errors.New("a draw can only be agreed by the last two players")
+	}
+	if gameBoardInProcess.DrawOfferedBy != "" {
+		return ProcessedGameBoard{}, errors.New("a draw is already offered")
+	}
+
+	gameBoardInProcess.DrawOfferedBy = event.playerOwner
+
+	return gameBoardInProcess, nil
+}
+
+func (event OfferDrawEvent) Encode() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         event.name,
+		"player_owner": event.playerOwner,
+	}
+}
+
+func (event OfferDrawEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+type AcceptDrawEvent struct {
+	name        string
+	playerOwner string
+}
+
+func NewAcceptDrawEvent(playerOwner string) AcceptDrawEvent {
+	return AcceptDrawEvent{
+		name:        ACCEPT_DRAW,
+		playerOwner: playerOwner,
+	}
+}
+
+func (event AcceptDrawEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoard) (ProcessedGameBoard, error) {
+	if !gameBoardInProcess.GameInProgress {
+		return ProcessedGameBoard{}, errors.New("the game is already over")
+	}
+	if !isPlayer(gameBoardInProcess.GameBoard.defenition, event.playerOwner) || gameBoardInProcess.EliminatedPlayers[event.playerOwner] {
+		return ProcessedGameBoard{}, errors.New("only a player of the game can accept a draw")
+	}
+	defenition := gameBoardInProcess.GameBoard.defenition
+	if gameBoardInProcess.DrawOfferedBy == "" || getPlayerSide(defenition, gameBoardInProcess.DrawOfferedBy) == getPlayerSide(defenition, event.playerOwner) {
+		return ProcessedGameBoard{}, errors.New("no draw was offered to the player")
+	}
+
+	gameBoardInProcess.GameInProgress = false
+	gameBoardInProcess.IsDraw = true
+	gameBoardInProcess.EndReason = END_REASON_DRAW_AGREEMENT
+	gameBoardInProcess.DrawOfferedBy = ""
+
+	return gameBoardInProcess, nil
+}
+
+func (event AcceptDrawEvent) Encode() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         event.name,
+		"player_owner": event.playerOwner,
+	}
+}
+
+func (event AcceptDrawEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
@@ -0,0 +1,76 @@
+package gamemechanics
+
+import (
+	"errors"
+	"time"
+)
+
+// EliminatePlayerEvent takes a player out of a game that still has more than two players,
+// the others keep playing and the turns of the eliminated player are skipped
+type EliminatePlayerEvent struct {
+	name        string
+	playerOwner string
+	reason      string
+	endTime     int64
+}
+
+func NewEliminatePlayerEvent(playerOwner string, reason string) EliminatePlayerEvent {
+	return EliminatePlayerEvent{
+		name:        ELIMINATE_PLAYER,
+		playerOwner: playerOwner,
+		reason:      reason,
+		endTime:     time.Now().UnixMilli(),
+	}
+}
+
+func (event EliminatePlayerEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoard) (ProcessedGameBoard, error) {
+	if !gameBoardInProcess.GameInProgress {
+		return ProcessedGameBoard{}, errors.New("the game is already over")
+	}
+	if !isPlayer(gameBoardInProcess.GameBoard.defenition, event.playerOwner) {
+		return ProcessedGameBoard{}, errors.New("only a player of the game can be eliminated")
+	}
+	if gameBoardInProcess.EliminatedPlayers[event.playerOwner] {
+		return ProcessedGameBoard{}, errors.New("the player is already eliminated")
+	}
+	if len(getActiveSides(gameBoardInProcess)) <= 2 {
+		return ProcessedGameBoard{}, errors.New("the game ends instead of eliminating one of the last two players")
+	}
+
+	gameBoardInProcess.EliminatedPlayers[event.playerOwner] = true
+	if gameBoardInProcess.DrawOfferedBy == event.playerOwner {
+		gameBoardInProcess.DrawOfferedBy = ""
+	}
+
+	if GetPlayerTurn(gameBoardInProcess.GameBoard) == event.playerOwner {
+		return startNextTurn(gameBoardInProcess, event.endTime), nil
+	}
+	return gameBoardInProcess, nil
+}
+
+func (event EliminatePlayerEvent) Encode() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         event.name,
+		"player_owner": event.playerOwner,
+		"reason":       event.reason,
+		"end_time":     event.endTime,
+	}
+}
+
+func (event EliminatePlayerEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
+	if event.reason, err = getString(anyMap, "reason"); err != nil {
+		return nil, err
+	}
+	if event.endTime, err = getInt64(anyMap, "end_time"); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
@@ -21,22 +21,46 @@ func NewEndTurnEvent(playerOwner string) EndTurnEvent {
 
 func (event EndTurnEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoard) (ProcessedGameBoard, error) {
 	currentPlayer := GetPlayerTurn(gameBoardInProcess.GameBoard)
-	expired := gameBoardInProcess.LastTurnEndTime+int64(gameBoardInProcess.GameBoard.defenition.TimePerTurn) < time.Now().UnixMilli()
+	expired := gameBoardInProcess.LastTurnEndTime+gameBoardInProcess.getTurnTimeLimit() < time.Now().UnixMilli()
 	if event.playerOwner != currentPlayer && !expired {
 		return ProcessedGameBoard{}, errors.New("cannot end the turn of another player")
 	}
 
-	gameBoardInProcess.GameBoard.Turn += 1
+	if event.playerOwner == currentPlayer {
+		gameBoardInProcess.ConsecutiveTimeouts[currentPlayer] = 0
+	} else {
+		gameBoardInProcess.ConsecutiveTimeouts[currentPlayer] += 1
+	}
+	gameBoardInProcess.chargeTurn(currentPlayer, event.endTime)
+
+	return startNextTurn(gameBoardInProcess, event.endTime), nil
+}
+
+// startNextTurn gives the turn to the next player that was not eliminated
+func startNextTurn(gameBoardInProcess ProcessedGameBoard, endTime int64) ProcessedGameBoard {
+	for i := 0; i < len(gameBoardInProcess.GameBoard.defenition.PlayerIds); i++ {
+		gameBoardInProcess.GameBoard.Turn += 1
+		if !gameBoardInProcess.EliminatedPlayers[GetPlayerTurn(gameBoardInProcess.GameBoard)] {
+			break
+		}
+	}
 
 	nextPlayerTurn := GetPlayerTurn(gameBoardInProcess.GameBoard)
-	gameBoardInProcess.AvailableShuffles[nextPlayerTurn] = 1
-	if gameBoardInProcess.GameBoard.ScoreBoard[nextPlayerTurn] < gameBoardInProcess.GameBoard.defenition.TargetScore {
-		gameBoardInProcess.GameBoard.ScoreBoard[nextPlayerTurn] += 1
+	gameBoardInProcess.AvailableShuffles[nextPlayerTurn] = gameBoardInProcess.GameBoard.defenition.ShufflesPerTurn
+	gameBoardInProcess.AvailableUndos[nextPlayerTurn] = gameBoardInProcess.GameBoard.defenition.UndosPerTurn
+	gameBoardInProcess.UndoablePlacements = nil
+	// the opponent had a whole turn to accept the draw
+	if gameBoardInProcess.DrawOfferedBy == nextPlayerTurn {
+		gameBoardInProcess.DrawOfferedBy = ""
+	}
+	nextSide := getPlayerSide(gameBoardInProcess.GameBoard.defenition, nextPlayerTurn)
+	if gameBoardInProcess.GameBoard.ScoreBoard[nextSide] < gameBoardInProcess.GameBoard.defenition.TargetScore {
+		gameBoardInProcess.GameBoard.ScoreBoard[nextSide] += 1
 	}
 
-	gameBoardInProcess.LastTurnEndTime = event.endTime
+	gameBoardInProcess.LastTurnEndTime = endTime
 
-	return gameBoardInProcess, nil
+	return gameBoardInProcess
 }
 
 func (event EndTurnEvent) Encode() map[string]interface{} {
@@ -47,10 +71,17 @@ func (event EndTurnEvent) Encode() map[string]interface{} {
 	}
 }
 
-func (event EndTurnEvent) Decode(anyMap map[string]interface{}) GameEvent {
-	event.name = anyMap["name"].(string)
-	event.playerOwner = anyMap["player_owner"].(string)
-	event.endTime = anyMap["end_time"].(int64)
+func (event EndTurnEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
+	if event.endTime, err = getInt64(anyMap, "end_time"); err != nil {
+		return nil, err
+	}
 
-	return event
+	return event, nil
 }
@@ -16,6 +16,15 @@ func (event FireDeflectorEvent) UpdateGameBoard(gameBoardInProcess ProcessedGame
 	gameBoardInProcess.GameBoard = gameBoard
 	gameBoardInProcess.LastDeflections = deflections
 
+	// each beam that exits scores for the owner of the edge, unless the owner is out of the game
+	for _, direction := range getExitDirections(deflections) {
+		side, ok := GetPlayerFromDirection(gameBoard.defenition, direction)
+		if ok && isActiveSide(gameBoardInProcess, side) {
+			gameBoardInProcess.GameBoard.ScoreBoard[side] += 1
+		}
+	}
+	gameBoardInProcess.UndoablePlacements = nil
+
 	return gameBoardInProcess, nil
 }
 
@@ -25,8 +34,11 @@ func (event FireDeflectorEvent) Encode() map[string]interface{} {
 	}
 }
 
-func (event FireDeflectorEvent) Decode(anyMap map[string]interface{}) GameEvent {
-	event.name = anyMap["name"].(string)
+func (event FireDeflectorEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
 
-	return event
+	return event, nil
 }
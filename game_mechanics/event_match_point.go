@@ -25,9 +25,14 @@ func (event MatchPointEvent) Encode() map[string]interface{} {
 	}
 }
 
-func (event MatchPointEvent) Decode(anyMap map[string]interface{}) GameEvent {
-	event.name = anyMap["name"].(string)
-	event.playerOwner = anyMap["player_owner"].(string)
+func (event MatchPointEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
 
-	return event
+	return event, nil
 }
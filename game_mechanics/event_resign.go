@@ -0,0 +1,67 @@
+package gamemechanics
+
+import "errors"
+
+type ResignEvent struct {
+	name        string
+	playerOwner string
+}
+
+func NewResignEvent(playerOwner string) ResignEvent {
+	return ResignEvent{
+		name:        RESIGN,
+		playerOwner: playerOwner,
+	}
+}
+
+// UpdateGameBoard ends the game with the opponent of the player as the winner,
+// a player can resign at any time, even during the turn of the opponent.
+// While more than two players are left, resigning eliminates the player instead.
+// In a team game the whole team of the player loses
+func (event ResignEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoard) (ProcessedGameBoard, error) {
+	if !gameBoardInProcess.GameInProgress {
+		return ProcessedGameBoard{}, errors.New("the game is already over")
+	}
+
+	defenition := gameBoardInProcess.GameBoard.defenition
+	if !isPlayer(defenition, event.playerOwner) || gameBoardInProcess.EliminatedPlayers[event.playerOwner] {
+		return ProcessedGameBoard{}, errors.New("only a player of the game can resign")
+	}
+
+	activeSides := getActiveSides(gameBoardInProcess)
+	if len(activeSides) > 2 {
+		return ProcessedGameBoard{}, errors.New("the player has more than one opponent left")
+	}
+
+	winner := ""
+	for _, side := range activeSides {
+		if side != getPlayerSide(defenition, event.playerOwner) {
+			winner = side
+		}
+	}
+
+	gameBoardInProcess.GameInProgress = false
+	gameBoardInProcess.Winner = winner
+	gameBoardInProcess.EndReason = END_REASON_RESIGN
+
+	return gameBoardInProcess, nil
+}
+
+func (event ResignEvent) Encode() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         event.name,
+		"player_owner": event.playerOwner,
+	}
+}
+
+func (event ResignEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
@@ -0,0 +1,184 @@
+package gamemechanics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const SCHEMA_VERSION = "schema_version"
+
+type eventUpcaster func(props map[string]interface{}) (map[string]interface{}, error)
+
+// eventSchema describes the current shape of a stored event type. When the encoded payload of an event changes,
+// its version is bumped and an upcaster is registered under the previous version to migrate the old payloads
+type eventSchema struct {
+	version   int
+	event     GameEvent
+	upcasters map[int]eventUpcaster
+}
+
+// the events stored before the envelope existed have no schema version,
+// their payload is the same as the one of version 1
+func legacyUpcaster(props map[string]interface{}) (map[string]interface{}, error) {
+	return props, nil
+}
+
+// before the reason was stored, a game could only be won by reaching the target score
+func winReasonUpcaster(props map[string]interface{}) (map[string]interface{}, error) {
+	props["reason"] = END_REASON_SCORE
+	return props, nil
+}
+
+// before portals, a pawn was never linked to a twin
+func createPawnTwinUpcaster(props map[string]interface{}) (map[string]interface{}, error) {
+	props["links_twin"] = false
+	return props, nil
+}
+
+var eventSchemas = map[string]eventSchema{
+	CREATE_PAWN: {
+		version:   2,
+		event:     CreatePawnEvent{},
+		upcasters: map[int]eventUpcaster{0: legacyUpcaster, 1: createPawnTwinUpcaster},
+	},
+	FIRE_DEFLECTOR: {
+		version:   1,
+		event:     FireDeflectorEvent{},
+		upcasters: map[int]eventUpcaster{0: legacyUpcaster},
+	},
+	SKIP_PAWN: {
+		version:   1,
+		event:     SkipPawnEvent{},
+		upcasters: map[int]eventUpcaster{0: legacyUpcaster},
+	},
+	END_TURN: {
+		version:   1,
+		event:     EndTurnEvent{},
+		upcasters: map[int]eventUpcaster{0: legacyUpcaster},
+	},
+	MATCH_POINT: {
+		version:   1,
+		event:     MatchPointEvent{},
+		upcasters: map[int]eventUpcaster{0: legacyUpcaster},
+	},
+	GAME_WIN: {
+		version:   2,
+		event:     WinEvent{},
+		upcasters: map[int]eventUpcaster{0: legacyUpcaster, 1: winReasonUpcaster},
+	},
+	// added after the envelope, it is always stored with its schema version
+	UNDO_PAWN: {
+		version:   1,
+		event:     UndoPawnEvent{},
+		upcasters: map[int]eventUpcaster{},
+	},
+	RESIGN: {
+		version:   1,
+		event:     ResignEvent{},
+		upcasters: map[int]eventUpcaster{},
+	},
+	OFFER_DRAW: {
+		version:   1,
+		event:     OfferDrawEvent{},
+		upcasters: map[int]eventUpcaster{},
+	},
+	ACCEPT_DRAW: {
+		version:   1,
+		event:     AcceptDrawEvent{},
+		upcasters: map[int]eventUpcaster{},
+	},
+	ELIMINATE_PLAYER: {
+		version:   1,
+		event:     EliminatePlayerEvent{},
+		upcasters: map[int]eventUpcaster{},
+	},
+}
+
+func EncodeGameEvent(event GameEvent) map[string]interface{} {
+	props := event.Encode()
+	name, _ := props["name"].(string)
+	props[SCHEMA_VERSION] = eventSchemas[name].version
+	return props
+}
+
+func DecodeGameEvent(props map[string]interface{}) (GameEvent, error) {
+	name, err := getString(props, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	schema, ok := eventSchemas[name]
+	if !ok {
+		return nil, errors.New("could not parse game event " + name)
+	}
+
+	version := 0
+	if _, ok := props[SCHEMA_VERSION]; ok {
+		version, err = getInt(props, SCHEMA_VERSION)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if version > schema.version {
+		return nil, fmt.Errorf("%s event has the unknown schema version %d", name, version)
+	}
+
+	upcasted := make(map[string]interface{})
+	for key, value := range props {
+		upcasted[key] = value
+	}
+
+	for ; version < schema.version; version++ {
+		upcaster, ok := schema.upcasters[version]
+		if !ok {
+			return nil, fmt.Errorf("%s event cannot be migrated from schema version %d", name, version)
+		}
+		upcasted, err = upcaster(upcasted)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return schema.event.Decode(upcasted)
+}
+
+func getString(props map[string]interface{}, key string) (string, error) {
+	value, ok := props[key].(string)
+	if !ok {
+		return "", fmt.Errorf("event field %s is not a string", key)
+	}
+	return value, nil
+}
+
+func getBool(props map[string]interface{}, key string) (bool, error) {
+	value, ok := props[key].(bool)
+	if !ok {
+		return false, fmt.Errorf("event field %s is not a boolean", key)
+	}
+	return value, nil
+}
+
+func getInt64(props map[string]interface{}, key string) (int64, error) {
+	switch value := props[key].(type) {
+	case int:
+		return int64(value), nil
+	case int32:
+		return int64(value), nil
+	case int64:
+		return value, nil
+	case float64:
+		// float64(math.MaxInt64) rounds up to 2^63, which is already out of range
+		if value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+			return 0, fmt.Errorf("event field %s is not an integer", key)
+		}
+		return int64(value), nil
+	}
+	return 0, fmt.Errorf("event field %s is not a number", key)
+}
+
+func getInt(props map[string]interface{}, key string) (int, error) {
+	value, err := getInt64(props, key)
+	return int(value), err
+}
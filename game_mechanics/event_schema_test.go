@@ -0,0 +1,150 @@
+package gamemechanics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodedEventsDecode(t *testing.T) {
+	events := []GameEvent{
+		NewCreatePawnEvent(position(1, 2), "red"),
+		NewCreateLinkedPawnEvent(position(1, 2), position(0, 0), "red"),
+		NewFireDeflectorEvent(),
+		NewSkipPawnEvent("red"),
+		NewEndTurnEvent("red"),
+		NewMatchPointEvent("blue"),
+		NewWinEvent("blue", END_REASON_SCORE),
+		NewUndoPawnEvent("red"),
+		NewResignEvent("red"),
+		NewOfferDrawEvent("red"),
+		NewAcceptDrawEvent("blue"),
+		NewEliminatePlayerEvent("red", END_REASON_FORFEIT),
+	}
+
+	for _, event := range events {
+		props := EncodeGameEvent(event)
+		if props[SCHEMA_VERSION] != eventSchemas[props["name"].(string)].version {
+			t.Errorf("Missing schema version in %v", props)
+		}
+
+		decoded, err := DecodeGameEvent(props)
+		if err != nil {
+			t.Errorf("Failed to decode %v: %v", props, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, event) {
+			t.Errorf("Decoded %v into %v", props, decoded)
+		}
+	}
+}
+
+func TestDecodeLegacyEvent(t *testing.T) {
+	event, err := DecodeGameEvent(map[string]interface{}{
+		"name":         CREATE_PAWN,
+		"position_x":   int32(1),
+		"position_y":   int32(2),
+		"player_owner": "red",
+	})
+	if err != nil {
+		t.Fatalf("Failed to decode legacy event: %v", err)
+	}
+	if !reflect.DeepEqual(event, NewCreatePawnEvent(position(1, 2), "red")) {
+		t.Errorf("Wrong legacy event %v", event)
+	}
+}
+
+func TestDecodeCreatePawnEventWithoutTwin(t *testing.T) {
+	event, err := DecodeGameEvent(map[string]interface{}{
+		"name":         CREATE_PAWN,
+		"position_x":   int32(1),
+		"position_y":   int32(2),
+		"player_owner": "red",
+		SCHEMA_VERSION: int32(1),
+	})
+	if err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if !reflect.DeepEqual(event, NewCreatePawnEvent(position(1, 2), "red")) {
+		t.Errorf("Wrong event %v", event)
+	}
+}
+
+func TestDecodeWinEventWithoutReason(t *testing.T) {
+	for _, version := range []interface{}{nil, int32(1)} {
+		props := map[string]interface{}{
+			"name":         GAME_WIN,
+			"player_owner": "red",
+		}
+		if version != nil {
+			props[SCHEMA_VERSION] = version
+		}
+
+		event, err := DecodeGameEvent(props)
+		if err != nil {
+			t.Fatalf("Failed to decode %v: %v", props, err)
+		}
+		if !reflect.DeepEqual(event, NewWinEvent("red", END_REASON_SCORE)) {
+			t.Errorf("Wrong win event %v", event)
+		}
+	}
+}
+
+func TestDecodeInvalidEvents(t *testing.T) {
+	invalidEvents := []map[string]interface{}{
+		{},
+		{"name": "unknown"},
+		{"name": CREATE_PAWN, "position_x": int32(1), "player_owner": "red"},
+		{"name": CREATE_PAWN, "position_x": "1", "position_y": int32(2), "player_owner": "red"},
+		{"name": END_TURN, "player_owner": "red", "end_time": "yesterday"},
+		{"name": END_TURN, "player_owner": "red", "end_time": 1650000000000.5},
+		{"name": END_TURN, "player_owner": "red", "end_time": 1e19},
+		{"name": GAME_WIN},
+		{"name": GAME_WIN, "player_owner": "red", SCHEMA_VERSION: int32(2)},
+		{"name": FIRE_DEFLECTOR, SCHEMA_VERSION: int32(99)},
+		{"name": FIRE_DEFLECTOR, SCHEMA_VERSION: "1"},
+	}
+
+	for _, props := range invalidEvents {
+		if _, err := DecodeGameEvent(props); err == nil {
+			t.Errorf("Decoded the invalid event %v", props)
+		}
+	}
+}
+
+func TestEventUpcasting(t *testing.T) {
+	eventSchemas["test_win"] = eventSchema{
+		version: 2,
+		event:   WinEvent{},
+		upcasters: map[int]eventUpcaster{
+			0: legacyUpcaster,
+			1: func(props map[string]interface{}) (map[string]interface{}, error) {
+				props["player_owner"] = props["winner"]
+				props["reason"] = END_REASON_SCORE
+				delete(props, "winner")
+				return props, nil
+			},
+		},
+	}
+	t.Cleanup(func() { delete(eventSchemas, "test_win") })
+
+	for _, version := range []interface{}{nil, int32(1)} {
+		props := map[string]interface{}{
+			"name":   "test_win",
+			"winner": "red",
+		}
+		if version != nil {
+			props[SCHEMA_VERSION] = version
+		}
+
+		event, err := DecodeGameEvent(props)
+		if err != nil {
+			t.Fatalf("Failed to upcast %v: %v", props, err)
+		}
+		if event.(WinEvent).playerOwner != "red" {
+			t.Errorf("Upcaster was not applied to %v", props)
+		}
+		if _, ok := props["player_owner"]; ok {
+			t.Errorf("Upcasting changed the stored payload")
+		}
+	}
+}
@@ -30,6 +30,8 @@ func (event SkipPawnEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoard
 	variants := gameBoardInProcess.PawnVariants[event.playerOwner]
 
 	gameBoardInProcess.PawnVariants[event.playerOwner] = gameBoardInProcess.VarianceFactory.GeneratePawnVariant(getPlayerDigest(gameBoardInProcess.GameBoard.defenition, event.playerOwner), len(variants)+1)
+	// the hand of the player changed, undoing a pawn would give back the wrong variant
+	gameBoardInProcess.UndoablePlacements = nil
 	return gameBoardInProcess, nil
 }
 
@@ -40,9 +42,14 @@ func (event SkipPawnEvent) Encode() map[string]interface{} {
 	}
 }
 
-func (event SkipPawnEvent) Decode(anyMap map[string]interface{}) GameEvent {
-	event.name = anyMap["name"].(string)
-	event.playerOwner = anyMap["player_owner"].(string)
+func (event SkipPawnEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
 
-	return event
+	return event, nil
 }
@@ -0,0 +1,73 @@
+package gamemechanics
+
+import "errors"
+
+type UndoPawnEvent struct {
+	name        string
+	playerOwner string
+}
+
+func NewUndoPawnEvent(playerOwner string) UndoPawnEvent {
+	return UndoPawnEvent{
+		name:        UNDO_PAWN,
+		playerOwner: playerOwner,
+	}
+}
+
+// UpdateGameBoard reverts the last pawn placed in the current turn, the pawn it replaced is put back,
+// the score point is given back and the variant of the pawn goes back to the player's hand
+func (event UndoPawnEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoard) (ProcessedGameBoard, error) {
+	currentPlayer := GetPlayerTurn(gameBoardInProcess.GameBoard)
+	if event.playerOwner != currentPlayer {
+		return ProcessedGameBoard{}, errors.New("out of turn action")
+	}
+
+	if gameBoardInProcess.AvailableUndos[event.playerOwner] <= 0 {
+		return ProcessedGameBoard{}, errors.New("out of undos for this turn")
+	}
+
+	placements := gameBoardInProcess.UndoablePlacements
+	if len(placements) == 0 {
+		return ProcessedGameBoard{}, errors.New("no pawn to undo")
+	}
+	placement := placements[len(placements)-1]
+
+	updatedPawns, err := removePawn(gameBoardInProcess.GameBoard.Pawns, placement.Position)
+	if err != nil {
+		return ProcessedGameBoard{}, err
+	}
+	if placement.ReplacedPawn != nil {
+		updatedPawns, err = addPawn(updatedPawns, *placement.ReplacedPawn)
+		if err != nil {
+			return ProcessedGameBoard{}, err
+		}
+	}
+	gameBoardInProcess.GameBoard.Pawns = updatedPawns
+
+	variants := gameBoardInProcess.PawnVariants[event.playerOwner]
+	gameBoardInProcess.PawnVariants[event.playerOwner] = append([]string{}, variants[:len(variants)-1]...)
+	gameBoardInProcess.GameBoard.ScoreBoard[getPlayerSide(gameBoardInProcess.GameBoard.defenition, event.playerOwner)] += 1
+	gameBoardInProcess.AvailableUndos[event.playerOwner] -= 1
+	gameBoardInProcess.UndoablePlacements = placements[:len(placements)-1]
+
+	return gameBoardInProcess, nil
+}
+
+func (event UndoPawnEvent) Encode() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         event.name,
+		"player_owner": event.playerOwner,
+	}
+}
+
+func (event UndoPawnEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
@@ -1,20 +1,34 @@
 package gamemechanics
 
+import "errors"
+
+// the reasons a game can end for, they are stored with the result of the game
+const (
+	END_REASON_SCORE          = "score"
+	END_REASON_FORFEIT        = "forfeit"
+	END_REASON_RESIGN         = "resign"
+	END_REASON_DRAW_AGREEMENT = "draw_agreement"
+	END_REASON_FLAG_FALL      = "flag_fall"
+)
+
 type WinEvent struct {
 	name        string
 	playerOwner string
+	reason      string
 }
 
-func NewWinEvent(playerOwner string) WinEvent {
+func NewWinEvent(playerOwner string, reason string) WinEvent {
 	return WinEvent{
 		name:        GAME_WIN,
 		playerOwner: playerOwner,
+		reason:      reason,
 	}
 }
 
 func (event WinEvent) UpdateGameBoard(gameBoardInProcess ProcessedGameBoard) (ProcessedGameBoard, error) {
 	gameBoardInProcess.GameInProgress = false
 	gameBoardInProcess.Winner = event.playerOwner
+	gameBoardInProcess.EndReason = event.reason
 
 	return gameBoardInProcess, nil
 }
@@ -23,12 +37,24 @@ func (event WinEvent) Encode() map[string]interface{} {
 	return map[string]interface{}{
 		"name":         event.name,
 		"player_owner": event.playerOwner,
+		"reason":       event.reason,
 	}
 }
 
-func (event WinEvent) Decode(anyMap map[string]interface{}) GameEvent {
-	event.name = anyMap["name"].(string)
-	event.playerOwner = anyMap["player_owner"].(string)
+func (event WinEvent) Decode(anyMap map[string]interface{}) (GameEvent, error) {
+	var err error
+	if event.name, err = getString(anyMap, "name"); err != nil {
+		return nil, err
+	}
+	if event.playerOwner, err = getString(anyMap, "player_owner"); err != nil {
+		return nil, err
+	}
+	if event.reason, err = getString(anyMap, "reason"); err != nil {
+		return nil, err
+	}
+	if event.reason == "" {
+		return nil, errors.New("a win event needs a reason")
+	}
 
-	return event
+	return event, nil
 }
@@ -12,25 +12,56 @@ const (
 	RIGHT
 )
 
+// BLOCKED is the direction of a deflector that was absorbed, it does not exit from any edge
+const BLOCKED = -1
+
 const (
 	PLAYER_ONE_SIDE = LEFT
 	PLAYER_TWO_SIDE = RIGHT
 )
 
+// boardEdges are the goals of the players, in the order of their PlayerIds. The edges without
+// a player, or whose player was eliminated, are neutral and nobody scores from them
+var boardEdges = []int{LEFT, RIGHT, UP, DOWN}
+
+const (
+	MIN_PLAYERS = 2
+	MAX_PLAYERS = 4
+)
+
 const (
 	SET_DURABILITY = "set_durability"
 	DESTROY_PAWM   = "destroy_pawn"
 )
 
 type GameBoardDefenition struct {
-	Id          string
-	PlayerIds   []string
-	YMax        int
-	XMax        int
-	Events      []GameEvent
-	TargetScore int
-	StartTime   int64
-	TimePerTurn int
+	Id           string
+	PlayerIds    []string
+	YMax         int
+	XMax         int
+	Events       []GameEvent
+	TargetScore  int
+	StartTime    int64
+	TimePerTurn  int
+	UndosPerTurn int
+	// PawnDurability is the number of deflections a new pawn takes before it is destroyed
+	PawnDurability  int
+	ShufflesPerTurn int
+	// BlockerChance is the percentage of the variants that are blockers
+	BlockerChance int
+	// SplitterChance is the percentage of the variants that are splitters
+	SplitterChance int
+	// PortalChance is the percentage of the variants that are portals
+	PortalChance int
+	// MaxTimeouts is the number of turns in a row a player can let run out before forfeiting, 0 never forfeits
+	MaxTimeouts     int
+	TimeControl     TimeControl
+	VarianceVersion int
+	Seed            string
+	ForkedFrom      string
+	ForkEventIndex  int
+	// Teams is empty in a free-for-all game
+	Teams []Team
 }
 
 type GameBoard struct {
@@ -65,10 +96,15 @@ func (gameBoard GameBoard) toMap() map[string]interface{} {
 	}
 }
 
+// Deflection is a step of a beam, the beams that a splitter branches off are
+// in the Branches of its deflection and each of them starts at the splitter.
+// The beam jumps to the deflections that are a Teleport, from the portal before them
 type Deflection struct {
 	Position    Position
 	ToDirection int
 	Events      []DeflectionEvent
+	Branches    [][]Deflection
+	Teleport    bool
 }
 
 func (deflection Deflection) toMap() map[string]interface{} {
@@ -77,13 +113,47 @@ func (deflection Deflection) toMap() map[string]interface{} {
 		events = append(events, deflection.Events[i].toMap())
 	}
 
+	branches := make([][]map[string]interface{}, 0)
+	for i := 0; i < len(deflection.Branches); i++ {
+		branches = append(branches, deflectionsToMap(deflection.Branches[i]))
+	}
+
 	return map[string]interface{}{
 		"position":    deflection.Position.toMap(),
 		"toDirection": deflection.ToDirection,
 		"events":      events,
+		"branches":    branches,
+		"teleport":    deflection.Teleport,
 	}
 }
 
+func deflectionsToMap(deflections []Deflection) []map[string]interface{} {
+	deflectionMaps := make([]map[string]interface{}, 0)
+	for i := 0; i < len(deflections); i++ {
+		deflectionMaps = append(deflectionMaps, deflections[i].toMap())
+	}
+	return deflectionMaps
+}
+
+// getExitDirections are the directions of the beams of a deflection that left the board, a beam
+// that was blocked does not exit. The branches come first since they are processed before the rest of the beam
+func getExitDirections(deflections []Deflection) []int {
+	directions := make([]int, 0)
+	for _, deflection := range deflections {
+		for _, branch := range deflection.Branches {
+			directions = append(directions, getExitDirections(branch)...)
+		}
+	}
+
+	if len(deflections) > 1 {
+		lastDirection := deflections[len(deflections)-1].ToDirection
+		if lastDirection != BLOCKED {
+			directions = append(directions, lastDirection)
+		}
+	}
+	return directions
+}
+
 type DeflectionEvent struct {
 	Name       string
 	Position   Position
@@ -104,25 +174,37 @@ type DirectedPosition struct {
 }
 
 func NewGameBoardDefinition(gameId string, playerIds []string) GameBoardDefenition {
+	return NewGameBoardDefinitionWithRules(gameId, playerIds, getStandardRules())
+}
+
+func NewGameBoardDefinitionWithRules(gameId string, playerIds []string, rules GameRules) GameBoardDefenition {
 	definition := GameBoardDefenition{
-		PlayerIds:   playerIds,
-		Id:          gameId,
-		YMax:        2,
-		XMax:        2,
-		Events:      make([]GameEvent, 0),
-		TargetScore: 6,
-		StartTime:   time.Now().UnixMilli(),
-		TimePerTurn: 45 * 1000,
+		PlayerIds:       playerIds,
+		Id:              gameId,
+		Events:          make([]GameEvent, 0),
+		StartTime:       time.Now().UnixMilli(),
+		VarianceVersion: CURRENT_VARIANCE_VERSION,
 	}
 
-	return definition
+	return rules.apply(definition)
 }
 
 func NewGameBoard(defenition GameBoardDefenition) (ProcessedGameBoard, error) {
-	return newGameBoard(defenition, RandomVarianceFactory{})
+	varianceFactory, err := getVarianceFactory(defenition)
+	if err != nil {
+		return ProcessedGameBoard{}, err
+	}
+	return newGameBoard(defenition, varianceFactory)
 }
 
 func newGameBoard(defenition GameBoardDefenition, varianceFactory VarianceFactory) (ProcessedGameBoard, error) {
+	events := defenition.Events
+	gameBoardInProcess := newEmptyGameBoard(defenition, varianceFactory)
+	return ProcessEvents(gameBoardInProcess, events)
+}
+
+// newEmptyGameBoard is the state of the game before any of its events are processed
+func newEmptyGameBoard(defenition GameBoardDefenition, varianceFactory VarianceFactory) ProcessedGameBoard {
 	height := defenition.YMax + 1
 	width := defenition.XMax + 1
 
@@ -135,12 +217,15 @@ func newGameBoard(defenition GameBoardDefenition, varianceFactory VarianceFactor
 	scoreBoard := make(map[string]int)
 	pawnVariants := make(map[string][]string)
 	for _, playerId := range defenition.PlayerIds {
-		scoreBoard[playerId] = 0
 		pawnVariants[playerId] = varianceFactory.GeneratePawnVariant(getPlayerDigest(defenition, playerId), 1)
 	}
-	scoreBoard[defenition.PlayerIds[0]] = 1
+	sides := getSides(defenition)
+	for _, side := range sides {
+		scoreBoard[side] = 0
+	}
+	// the side that plays the first turn
+	scoreBoard[sides[0]] = 1
 
-	events := defenition.Events
 	defenition.Events = make([]GameEvent, 0)
 	gameBoard := GameBoard{
 		defenition: defenition,
@@ -151,14 +236,28 @@ func newGameBoard(defenition GameBoardDefenition, varianceFactory VarianceFactor
 
 	playersInMatchPoint := make(map[string]bool)
 	availableShuffles := make(map[string]int)
+	availableUndos := make(map[string]int)
+	consecutiveTimeouts := make(map[string]int)
+	clocks := make(map[string]int64)
+	eliminatedPlayers := make(map[string]bool)
+	for _, side := range sides {
+		playersInMatchPoint[side] = false
+	}
 	for _, playerId := range gameBoard.defenition.PlayerIds {
-		playersInMatchPoint[playerId] = false
-		availableShuffles[playerId] = 1
+		availableShuffles[playerId] = defenition.ShufflesPerTurn
+		availableUndos[playerId] = defenition.UndosPerTurn
+		consecutiveTimeouts[playerId] = 0
+		clocks[playerId] = defenition.TimeControl.Bank
+		eliminatedPlayers[playerId] = false
 	}
 
 	gameBoardInProcess := ProcessedGameBoard{
 		PlayersInMatchPoint:  playersInMatchPoint,
 		AvailableShuffles:    availableShuffles,
+		AvailableUndos:       availableUndos,
+		ConsecutiveTimeouts:  consecutiveTimeouts,
+		Clocks:               clocks,
+		EliminatedPlayers:    eliminatedPlayers,
 		GameBoard:            gameBoard,
 		ProcessingEventIndex: 0,
 		VarianceFactory:      varianceFactory,
@@ -166,7 +265,7 @@ func newGameBoard(defenition GameBoardDefenition, varianceFactory VarianceFactor
 		PawnVariants:         pawnVariants,
 		LastTurnEndTime:      defenition.StartTime,
 	}
-	return ProcessEvents(gameBoardInProcess, events)
+	return gameBoardInProcess
 }
 
 func ProcessEvents(gameBoardInProcess ProcessedGameBoard, events []GameEvent) (ProcessedGameBoard, error) {
@@ -241,6 +340,7 @@ func removePawn(pawns [][]*Pawn, position Position) ([][]*Pawn, error) {
 	if !isWithinBoard(pawns, position) {
 		return pawns, errors.New("invalid pawn position")
 	}
+	unlinkTwin(pawns, pawns[position.Y][position.X])
 	pawns[position.Y][position.X] = nil
 
 	return pawns, nil
@@ -251,10 +351,27 @@ func addPawn(pawns [][]*Pawn, newPawn Pawn) ([][]*Pawn, error) {
 		return pawns, errors.New("invalid pawn position")
 	}
 
+	unlinkTwin(pawns, pawns[newPawn.Position.Y][newPawn.Position.X])
 	pawns[newPawn.Position.Y][newPawn.Position.X] = &newPawn
+	if newPawn.Twin != nil && isWithinBoard(pawns, *newPawn.Twin) {
+		if twin := pawns[newPawn.Twin.Y][newPawn.Twin.X]; twin != nil {
+			twinPosition := newPawn.Position
+			twin.Twin = &twinPosition
+		}
+	}
 	return pawns, nil
 }
 
+// unlinkTwin leaves the twin of a portal that is taken off the board without a twin
+func unlinkTwin(pawns [][]*Pawn, pawn *Pawn) {
+	if pawn == nil || pawn.Twin == nil || !isWithinBoard(pawns, *pawn.Twin) {
+		return
+	}
+	if twin := pawns[pawn.Twin.Y][pawn.Twin.X]; twin != nil && twin.Twin != nil && *twin.Twin == pawn.Position {
+		twin.Twin = nil
+	}
+}
+
 func (gameBoard GameBoard) getNextPawn(currentPosition Position, currentDirection int) (*Pawn, error) {
 
 	if currentDirection == UP {
@@ -318,13 +435,23 @@ func getEdgeDeflection(gameBoard GameBoard, lastDeflection Deflection) Deflectio
 	}
 }
 
+// ProcessDeflection follows the beam of the deflector until it exits the board, the pawns it meets lose durability.
+// A beam is a tree when it meets splitters, and each of its branches exits on its own
 func ProcessDeflection(gameBoard GameBoard, current DirectedPosition) (GameBoard, []Deflection) {
+	return processBeam(gameBoard, current, make(map[DirectedPosition]bool))
+}
+
+// processBeam records the portals the beam went through on its way from the deflector in teleports,
+// each branch gets its own copy. A beam that would go through the same portal in the same direction
+// again is in a loop and is absorbed
+func processBeam(gameBoard GameBoard, current DirectedPosition, teleports map[DirectedPosition]bool) (GameBoard, []Deflection) {
 	currentPosition, currentDirection := current.Position, current.Direction
 	deflections := []Deflection{
 		{
 			Position:    currentPosition,
 			ToDirection: currentDirection,
 			Events:      make([]DeflectionEvent, 0),
+			Branches:    make([][]Deflection, 0),
 		},
 	}
 
@@ -333,8 +460,20 @@ func ProcessDeflection(gameBoard GameBoard, current DirectedPosition) (GameBoard
 		if err != nil {
 			break
 		}
+		previousDirection := currentDirection
 		currentPosition = pawn.Position
 		currentDirection = pawn.getDeflectedDirection(currentDirection)
+
+		var teleport *Position
+		if pawn.Name == PORTAL && pawn.Twin != nil {
+			entry := DirectedPosition{Position: pawn.Position, Direction: previousDirection}
+			if teleports[entry] {
+				currentDirection = BLOCKED
+			} else {
+				teleports[entry] = true
+				teleport = pawn.Twin
+			}
+		}
 		pawn.Durability -= 1
 		events := make([]DeflectionEvent, 0)
 		events = append(events, DeflectionEvent{
@@ -355,44 +494,152 @@ func ProcessDeflection(gameBoard GameBoard, current DirectedPosition) (GameBoard
 			}
 		}
 
+		branches := make([][]Deflection, 0)
+		if branchDirection, ok := pawn.getBranchDirection(previousDirection); ok {
+			var branch []Deflection
+			gameBoard, branch = processBeam(gameBoard, DirectedPosition{
+				Position:  currentPosition,
+				Direction: branchDirection,
+			}, copyTeleports(teleports))
+			branches = append(branches, branch)
+		}
+
 		deflections = append(deflections, Deflection{
 			Position:    currentPosition,
 			ToDirection: currentDirection,
 			Events:      events,
+			Branches:    branches,
 		})
+
+		if currentDirection == BLOCKED {
+			return gameBoard, deflections
+		}
+
+		if teleport != nil {
+			currentPosition = *teleport
+			deflections = append(deflections, Deflection{
+				Position:    currentPosition,
+				ToDirection: currentDirection,
+				Events:      make([]DeflectionEvent, 0),
+				Branches:    make([][]Deflection, 0),
+				Teleport:    true,
+			})
+		}
 	}
 
 	lastDeflection := deflections[len(deflections)-1]
 	deflections = append(deflections, getEdgeDeflection(gameBoard, lastDeflection))
 
-	playerId, ok := GetPlayerFromDirection(gameBoard.defenition, lastDeflection.ToDirection)
-	if ok {
-		gameBoard.ScoreBoard[playerId] += 1
-	}
-
 	return gameBoard, deflections
 }
 
+func copyTeleports(teleports map[DirectedPosition]bool) map[DirectedPosition]bool {
+	copied := make(map[DirectedPosition]bool)
+	for key, value := range teleports {
+		copied[key] = value
+	}
+	return copied
+}
+
+// GetPlayerFromDirection is the side whose edge a deflection going in the direction exits from,
+// the player in a free-for-all game and the team in a team game
 func GetPlayerFromDirection(defenition GameBoardDefenition, direction int) (string, bool) {
-	if direction == LEFT {
-		return defenition.PlayerIds[0], true
-	} else if direction == RIGHT {
-		return defenition.PlayerIds[1], true
+	for i, side := range getSides(defenition) {
+		if i < len(boardEdges) && boardEdges[i] == direction {
+			return side, true
+		}
 	}
 	return "", false
 }
 
+func getPlayerEdges(defenition GameBoardDefenition) map[string]int {
+	playerEdges := make(map[string]int)
+	for i, side := range getSides(defenition) {
+		if i < len(boardEdges) {
+			playerEdges[side] = boardEdges[i]
+		}
+	}
+	return playerEdges
+}
+
+func isPlayer(defenition GameBoardDefenition, playerId string) bool {
+	for _, id := range defenition.PlayerIds {
+		if id == playerId {
+			return true
+		}
+	}
+	return false
+}
+
 func GetPlayerTurn(gameBoard GameBoard) string {
+	if isTeamGame(gameBoard.defenition) {
+		teams := gameBoard.defenition.Teams
+		team := teams[gameBoard.Turn%len(teams)]
+		return team.PlayerIds[(gameBoard.Turn/len(teams))%len(team.PlayerIds)]
+	}
 	return gameBoard.defenition.PlayerIds[gameBoard.Turn%len(gameBoard.defenition.PlayerIds)]
 }
 
+// getVarianceSeed is the string the variance of the game derives from,
+// a fork keeps the seed of the game it was forked from so that it plays out the same.
+// The games stored before the seed existed fall back to their id
+func getVarianceSeed(defenition GameBoardDefenition) string {
+	if defenition.Seed != "" {
+		return defenition.Seed
+	}
+	return defenition.Id
+}
+
 func getPlayerDigest(defenition GameBoardDefenition, playerId string) string {
-	return defenition.Id + playerId
+	return getVarianceSeed(defenition) + playerId
+}
+
+// getActivePlayers are the players that were not eliminated, in the order of their turns
+func getActivePlayers(gameBoardInProcess ProcessedGameBoard) []string {
+	activePlayers := make([]string, 0)
+	for _, playerId := range gameBoardInProcess.GameBoard.defenition.PlayerIds {
+		if !gameBoardInProcess.EliminatedPlayers[playerId] {
+			activePlayers = append(activePlayers, playerId)
+		}
+	}
+	return activePlayers
+}
+
+// getLossEvents makes the side of the player lose for the reason. While more than two sides are left
+// the player is only eliminated, otherwise the last side left wins the game
+func getLossEvents(gameBoardInProcess ProcessedGameBoard, playerId string, reason string) []GameEvent {
+	activeSides := getActiveSides(gameBoardInProcess)
+	if len(activeSides) > 2 {
+		return []GameEvent{NewEliminatePlayerEvent(playerId, reason)}
+	}
+
+	side := getPlayerSide(gameBoardInProcess.GameBoard.defenition, playerId)
+	for _, activeSide := range activeSides {
+		if activeSide != side {
+			return []GameEvent{NewWinEvent(activeSide, reason)}
+		}
+	}
+	return []GameEvent{}
+}
+
+// GetForfeitEvents makes a player who let too many turns in a row run out lose
+func GetForfeitEvents(gameBoardInProcess ProcessedGameBoard) []GameEvent {
+	maxTimeouts := gameBoardInProcess.GameBoard.defenition.MaxTimeouts
+	if maxTimeouts <= 0 {
+		return []GameEvent{}
+	}
+
+	for _, playerId := range getActivePlayers(gameBoardInProcess) {
+		if gameBoardInProcess.ConsecutiveTimeouts[playerId] >= maxTimeouts {
+			return getLossEvents(gameBoardInProcess, playerId, END_REASON_FORFEIT)
+		}
+	}
+	return []GameEvent{}
 }
 
 func GetMatchPointEvents(gameBoardInPrccess ProcessedGameBoard) []GameEvent {
 	matchPointEvents := make([]GameEvent, 0)
-	for _, playerId := range gameBoardInPrccess.GameBoard.defenition.PlayerIds {
+	for _, playerId := range getActiveSides(gameBoardInPrccess) {
 		if gameBoardInPrccess.GameBoard.ScoreBoard[playerId] >= gameBoardInPrccess.GameBoard.defenition.TargetScore && !gameBoardInPrccess.PlayersInMatchPoint[playerId] {
 			matchPointEvents = append(matchPointEvents, NewMatchPointEvent(playerId))
 		}
@@ -1,14 +1,17 @@
 package gamemechanics
 
-import "errors"
-
 const (
-	CREATE_PAWN    = "create_pawn"
-	FIRE_DEFLECTOR = "fire_deflector"
-	SKIP_PAWN      = "skip_pawn"
-	END_TURN       = "end_turn"
-	MATCH_POINT    = "match_point"
-	GAME_WIN       = "game_win"
+	CREATE_PAWN      = "create_pawn"
+	FIRE_DEFLECTOR   = "fire_deflector"
+	SKIP_PAWN        = "skip_pawn"
+	END_TURN         = "end_turn"
+	MATCH_POINT      = "match_point"
+	GAME_WIN         = "game_win"
+	UNDO_PAWN        = "undo_pawn"
+	RESIGN           = "resign"
+	OFFER_DRAW       = "offer_draw"
+	ACCEPT_DRAW      = "accept_draw"
+	ELIMINATE_PLAYER = "eliminate_player"
 )
 
 type ProcessedGameBoard struct {
@@ -22,6 +25,107 @@ type ProcessedGameBoard struct {
 	Winner               string
 	PawnVariants         map[string][]string
 	LastTurnEndTime      int64
+	AvailableUndos       map[string]int
+	UndoablePlacements   []PawnPlacement
+	DrawOfferedBy        string
+	IsDraw               bool
+	ConsecutiveTimeouts  map[string]int
+	EndReason            string
+	Clocks               map[string]int64
+	EliminatedPlayers    map[string]bool
+}
+
+// PawnPlacement is a pawn placed in the current turn that can still be undone,
+// along with the pawn it replaced on the board if there was one
+type PawnPlacement struct {
+	Position     Position
+	ReplacedPawn *Pawn
+}
+
+// clone gives a copy that can process more events without changing this game board
+func (processedGameBoard ProcessedGameBoard) clone() ProcessedGameBoard {
+	cloned := processedGameBoard
+
+	events := make([]GameEvent, len(processedGameBoard.GameBoard.defenition.Events))
+	copy(events, processedGameBoard.GameBoard.defenition.Events)
+	cloned.GameBoard.defenition.Events = events
+
+	pawns := make([][]*Pawn, len(processedGameBoard.GameBoard.Pawns))
+	for i := 0; i < len(pawns); i++ {
+		pawns[i] = make([]*Pawn, len(processedGameBoard.GameBoard.Pawns[i]))
+		for j := 0; j < len(pawns[i]); j++ {
+			if pawn := processedGameBoard.GameBoard.Pawns[i][j]; pawn != nil {
+				pawnCopy := *pawn
+				pawns[i][j] = &pawnCopy
+			}
+		}
+	}
+	cloned.GameBoard.Pawns = pawns
+	cloned.GameBoard.ScoreBoard = processedGameBoard.GameBoard.CopyScoreBoard()
+
+	cloned.PlayersInMatchPoint = make(map[string]bool)
+	for key, value := range processedGameBoard.PlayersInMatchPoint {
+		cloned.PlayersInMatchPoint[key] = value
+	}
+	cloned.AvailableShuffles = make(map[string]int)
+	for key, value := range processedGameBoard.AvailableShuffles {
+		cloned.AvailableShuffles[key] = value
+	}
+	cloned.Clocks = processedGameBoard.copyClocks()
+	cloned.ConsecutiveTimeouts = make(map[string]int)
+	for key, value := range processedGameBoard.ConsecutiveTimeouts {
+		cloned.ConsecutiveTimeouts[key] = value
+	}
+	cloned.EliminatedPlayers = make(map[string]bool)
+	for key, value := range processedGameBoard.EliminatedPlayers {
+		cloned.EliminatedPlayers[key] = value
+	}
+	cloned.AvailableUndos = make(map[string]int)
+	for key, value := range processedGameBoard.AvailableUndos {
+		cloned.AvailableUndos[key] = value
+	}
+	cloned.UndoablePlacements = make([]PawnPlacement, 0)
+	for _, placement := range processedGameBoard.UndoablePlacements {
+		if placement.ReplacedPawn != nil {
+			replacedPawn := *placement.ReplacedPawn
+			placement.ReplacedPawn = &replacedPawn
+		}
+		cloned.UndoablePlacements = append(cloned.UndoablePlacements, placement)
+	}
+	cloned.PawnVariants = make(map[string][]string)
+	for key, value := range processedGameBoard.PawnVariants {
+		cloned.PawnVariants[key] = append([]string{}, value...)
+	}
+	cloned.LastDeflections = append([]Deflection{}, processedGameBoard.LastDeflections...)
+
+	return cloned
+}
+
+// getEliminatedPlayers lists the eliminated players in the order of their turns
+func (processedGameBoard ProcessedGameBoard) getEliminatedPlayers() []string {
+	eliminatedPlayers := make([]string, 0)
+	for _, playerId := range processedGameBoard.GameBoard.defenition.PlayerIds {
+		if processedGameBoard.EliminatedPlayers[playerId] {
+			eliminatedPlayers = append(eliminatedPlayers, playerId)
+		}
+	}
+	return eliminatedPlayers
+}
+
+// getWinningPlayers are all the players of the side that won
+func (processedGameBoard ProcessedGameBoard) getWinningPlayers() []string {
+	if processedGameBoard.Winner == "" {
+		return []string{}
+	}
+	return getSidePlayers(processedGameBoard.GameBoard.defenition, processedGameBoard.Winner)
+}
+
+// getTurnDeadline is when the current turn runs out, 0 once the game is over
+func (processedGameBoard ProcessedGameBoard) getTurnDeadline() int64 {
+	if !processedGameBoard.GameInProgress {
+		return 0
+	}
+	return processedGameBoard.LastTurnEndTime + processedGameBoard.getTurnTimeLimit()
 }
 
 func (processedGameBoard ProcessedGameBoard) toMap() map[string]interface{} {
@@ -32,9 +136,18 @@ func (processedGameBoard ProcessedGameBoard) toMap() map[string]interface{} {
 		deflections = append(deflections, processedGameBoard.LastDeflections[i].toMap())
 	}
 
+	teams := make([]map[string]interface{}, 0)
+	for _, team := range defenition.Teams {
+		teams = append(teams, team.toMap())
+	}
+
 	return map[string]interface{}{
 		"gameId":            defenition.Id,
 		"playerIds":         defenition.PlayerIds,
+		"playerEdges":       getPlayerEdges(defenition),
+		"eliminatedPlayers": processedGameBoard.EliminatedPlayers,
+		"teams":             teams,
+		"winningPlayers":    processedGameBoard.getWinningPlayers(),
 		"timePerTurn":       defenition.TimePerTurn,
 		"lastTurnEndTime":   processedGameBoard.LastTurnEndTime,
 		"gameBoard":         processedGameBoard.GameBoard.toMap(),
@@ -43,30 +156,21 @@ func (processedGameBoard ProcessedGameBoard) toMap() map[string]interface{} {
 		"targetScore":       defenition.TargetScore,
 		"matchPointPlayers": processedGameBoard.PlayersInMatchPoint,
 		"availableShuffles": processedGameBoard.AvailableShuffles,
+		"availableUndos":    processedGameBoard.AvailableUndos,
+		"drawOfferedBy":     processedGameBoard.DrawOfferedBy,
+		"isDraw":            processedGameBoard.IsDraw,
+		"endReason":         processedGameBoard.EndReason,
+		"clocks":            processedGameBoard.Clocks,
+		"timeControl":       defenition.TimeControl.toMap(),
+		"rules":             getGameRules(defenition).toMap(),
 		"deflections":       deflections,
+		"forkedFrom":        defenition.ForkedFrom,
+		"forkEventIndex":    defenition.ForkEventIndex,
 	}
 }
 
 type GameEvent interface {
 	UpdateGameBoard(gameBoardInProcess ProcessedGameBoard) (ProcessedGameBoard, error)
 	Encode() map[string]interface{}
-	Decode(anyMap map[string]interface{}) GameEvent
-}
-
-func DecodeGameEvent(props map[string]interface{}) (GameEvent, error) {
-	if props["name"] == CREATE_PAWN {
-		return (CreatePawnEvent{}).Decode(props), nil
-	} else if props["name"] == FIRE_DEFLECTOR {
-		return (FireDeflectorEvent{}).Decode(props), nil
-	} else if props["name"] == SKIP_PAWN {
-		return (SkipPawnEvent{}).Decode(props), nil
-	} else if props["name"] == END_TURN {
-		return (EndTurnEvent{}).Decode(props), nil
-	} else if props["name"] == MATCH_POINT {
-		return (MatchPointEvent{}).Decode(props), nil
-	} else if props["name"] == GAME_WIN {
-		return (WinEvent{}).Decode(props), nil
-	}
-
-	return CreatePawnEvent{}, errors.New("could not parse game event")
+	Decode(anyMap map[string]interface{}) (GameEvent, error)
 }
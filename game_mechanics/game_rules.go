@@ -0,0 +1,177 @@
+package gamemechanics
+
+import (
+	"fmt"
+)
+
+const (
+	QUICK_RULES    = "quick"
+	STANDARD_RULES = "standard"
+	LONG_RULES     = "long"
+)
+
+// GameRules are chosen when a game is created, they are stored in its defenition
+// so that the events of the game are always replayed with the same rules
+type GameRules struct {
+	XMax            int
+	YMax            int
+	TargetScore     int
+	TimePerTurn     int
+	PawnDurability  int
+	ShufflesPerTurn int
+	UndosPerTurn    int
+	MaxTimeouts     int
+	BlockerChance   int
+	SplitterChance  int
+	PortalChance    int
+	TimeControl     TimeControl
+}
+
+var gameRulesPresets = map[string]GameRules{
+	QUICK_RULES: {
+		XMax:            2,
+		YMax:            2,
+		TargetScore:     4,
+		TimePerTurn:     20 * 1000,
+		PawnDurability:  3,
+		ShufflesPerTurn: 1,
+		UndosPerTurn:    1,
+		MaxTimeouts:     2,
+	},
+	STANDARD_RULES: {
+		XMax:            2,
+		YMax:            2,
+		TargetScore:     6,
+		TimePerTurn:     45 * 1000,
+		PawnDurability:  5,
+		ShufflesPerTurn: 1,
+		UndosPerTurn:    1,
+		MaxTimeouts:     3,
+	},
+	LONG_RULES: {
+		XMax:            4,
+		YMax:            4,
+		TargetScore:     10,
+		TimePerTurn:     90 * 1000,
+		PawnDurability:  7,
+		ShufflesPerTurn: 2,
+		UndosPerTurn:    2,
+		MaxTimeouts:     3,
+		BlockerChance:   10,
+	},
+}
+
+// GameRulesError is returned when the rules of a new game are not playable
+type GameRulesError struct {
+	Message string
+}
+
+func (err GameRulesError) Error() string {
+	return "invalid game rules: " + err.Message
+}
+
+// GetGameRulesPreset returns the rules of a matchmaking mode, an empty name is the standard mode
+func GetGameRulesPreset(name string) (GameRules, error) {
+	if name == "" {
+		name = STANDARD_RULES
+	}
+
+	rules, ok := gameRulesPresets[name]
+	if !ok {
+		return GameRules{}, GameRulesError{Message: "unknown preset " + name}
+	}
+	return rules, nil
+}
+
+func getStandardRules() GameRules {
+	return gameRulesPresets[STANDARD_RULES]
+}
+
+func checkRuleRange(name string, value int64, min int64, max int64) error {
+	if value < min || value > max {
+		return GameRulesError{Message: fmt.Sprintf("%s must be between %d and %d", name, min, max)}
+	}
+	return nil
+}
+
+func (rules GameRules) Validate() error {
+	checks := []error{
+		checkRuleRange("xMax", int64(rules.XMax), 1, 10),
+		checkRuleRange("yMax", int64(rules.YMax), 1, 10),
+		checkRuleRange("targetScore", int64(rules.TargetScore), 2, 50),
+		checkRuleRange("timePerTurn", int64(rules.TimePerTurn), 5*1000, 10*60*1000),
+		checkRuleRange("pawnDurability", int64(rules.PawnDurability), 1, 20),
+		checkRuleRange("shufflesPerTurn", int64(rules.ShufflesPerTurn), 0, 5),
+		checkRuleRange("undosPerTurn", int64(rules.UndosPerTurn), 0, 5),
+		checkRuleRange("maxTimeouts", int64(rules.MaxTimeouts), 0, 10),
+		checkRuleRange("blockerChance", int64(rules.BlockerChance), 0, 50),
+		checkRuleRange("splitterChance", int64(rules.SplitterChance), 0, 50),
+		checkRuleRange("portalChance", int64(rules.PortalChance), 0, 50),
+		checkRuleRange("timeControl.bank", rules.TimeControl.Bank, 0, 60*60*1000),
+		checkRuleRange("timeControl.increment", rules.TimeControl.Increment, 0, 10*60*1000),
+		checkRuleRange("timeControl.delay", rules.TimeControl.Delay, 0, 10*60*1000),
+	}
+	for _, err := range checks {
+		if err != nil {
+			return err
+		}
+	}
+
+	if rules.BlockerChance+rules.SplitterChance+rules.PortalChance > 50 {
+		return GameRulesError{Message: "blockerChance, splitterChance and portalChance can only add up to 50"}
+	}
+	if !rules.TimeControl.isEnabled() && (rules.TimeControl.Increment != 0 || rules.TimeControl.Delay != 0) {
+		return GameRulesError{Message: "timeControl needs a bank to have an increment or a delay"}
+	}
+	return nil
+}
+
+func (rules GameRules) apply(defenition GameBoardDefenition) GameBoardDefenition {
+	defenition.XMax = rules.XMax
+	defenition.YMax = rules.YMax
+	defenition.TargetScore = rules.TargetScore
+	defenition.TimePerTurn = rules.TimePerTurn
+	defenition.PawnDurability = rules.PawnDurability
+	defenition.ShufflesPerTurn = rules.ShufflesPerTurn
+	defenition.UndosPerTurn = rules.UndosPerTurn
+	defenition.MaxTimeouts = rules.MaxTimeouts
+	defenition.BlockerChance = rules.BlockerChance
+	defenition.SplitterChance = rules.SplitterChance
+	defenition.PortalChance = rules.PortalChance
+	defenition.TimeControl = rules.TimeControl
+	return defenition
+}
+
+func (rules GameRules) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"xMax":            rules.XMax,
+		"yMax":            rules.YMax,
+		"targetScore":     rules.TargetScore,
+		"timePerTurn":     rules.TimePerTurn,
+		"pawnDurability":  rules.PawnDurability,
+		"shufflesPerTurn": rules.ShufflesPerTurn,
+		"undosPerTurn":    rules.UndosPerTurn,
+		"maxTimeouts":     rules.MaxTimeouts,
+		"blockerChance":   rules.BlockerChance,
+		"splitterChance":  rules.SplitterChance,
+		"portalChance":    rules.PortalChance,
+		"timeControl":     rules.TimeControl.toMap(),
+	}
+}
+
+func getGameRules(defenition GameBoardDefenition) GameRules {
+	return GameRules{
+		XMax:            defenition.XMax,
+		YMax:            defenition.YMax,
+		TargetScore:     defenition.TargetScore,
+		TimePerTurn:     defenition.TimePerTurn,
+		PawnDurability:  defenition.PawnDurability,
+		ShufflesPerTurn: defenition.ShufflesPerTurn,
+		UndosPerTurn:    defenition.UndosPerTurn,
+		MaxTimeouts:     defenition.MaxTimeouts,
+		BlockerChance:   defenition.BlockerChance,
+		SplitterChance:  defenition.SplitterChance,
+		PortalChance:    defenition.PortalChance,
+		TimeControl:     defenition.TimeControl,
+	}
+}
@@ -0,0 +1,102 @@
+package gamemechanics
+
+import (
+	"errors"
+	"projectdeflector/game/repositories"
+	"testing"
+)
+
+func TestGameRulesPresetsAreValid(t *testing.T) {
+	for _, name := range []string{"", QUICK_RULES, STANDARD_RULES, LONG_RULES} {
+		rules, err := GetGameRulesPreset(name)
+		if err != nil {
+			t.Fatalf("Failed to get preset %s: %v", name, err)
+		}
+		if err := rules.Validate(); err != nil {
+			t.Errorf("Preset %s is invalid: %v", name, err)
+		}
+	}
+
+	if _, err := GetGameRulesPreset("blitz"); err == nil {
+		t.Errorf("Got an unknown preset")
+	}
+}
+
+func TestInvalidGameRules(t *testing.T) {
+	invalidRules := []func(rules *GameRules){
+		func(rules *GameRules) { rules.XMax = 0 },
+		func(rules *GameRules) { rules.YMax = 100 },
+		func(rules *GameRules) { rules.TargetScore = 1 },
+		func(rules *GameRules) { rules.TimePerTurn = 0 },
+		func(rules *GameRules) { rules.PawnDurability = 0 },
+		func(rules *GameRules) { rules.ShufflesPerTurn = -1 },
+		func(rules *GameRules) { rules.UndosPerTurn = 6 },
+		func(rules *GameRules) { rules.MaxTimeouts = -1 },
+		func(rules *GameRules) { rules.BlockerChance = 51 },
+		func(rules *GameRules) { rules.BlockerChance, rules.SplitterChance = 30, 30 },
+		func(rules *GameRules) { rules.SplitterChance, rules.PortalChance = 20, 40 },
+		func(rules *GameRules) { rules.TimeControl.Increment = 1000 },
+	}
+
+	useCase := newTestUseCase(t)
+	for i, invalidate := range invalidRules {
+		rules := getStandardRules()
+		invalidate(&rules)
+
+		_, err := useCase.CreateNewGame([]string{"red", "blue"}, rules)
+		var rulesError GameRulesError
+		if !errors.As(err, &rulesError) {
+			t.Errorf("Rules %d were accepted: %v", i, err)
+		}
+	}
+}
+
+func TestGameRulesAreStored(t *testing.T) {
+	useCase := newTestUseCase(t)
+
+	rules := getStandardRules()
+	rules.XMax = 4
+	rules.PawnDurability = 2
+	rules.ShufflesPerTurn = 0
+	gameId, err := useCase.CreateNewGame([]string{"red", "blue"}, rules)
+	if err != nil {
+		t.Fatalf("Failed to create game: %v", err)
+	}
+
+	_, err = useCase.AddPawn(gameId, AddPawnRequest{X: 4, Y: 0, PlayerSide: "red"})
+	if err != nil {
+		t.Fatalf("Failed to add pawn: %v", err)
+	}
+	if _, err := useCase.Shuffle(gameId, "red", nil); err == nil {
+		t.Errorf("Shuffled without any shuffle per turn")
+	}
+
+	game, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if getGameRules(game.ProcessedGameBoard.GameBoard.defenition) != rules {
+		t.Errorf("Expected the rules %v, got %v", rules, getGameRules(game.ProcessedGameBoard.GameBoard.defenition))
+	}
+	pawn, err := game.ProcessedGameBoard.GameBoard.GetPawn(position(4, 0))
+	if err != nil || pawn.Durability != 2 {
+		t.Errorf("The pawn was not placed with the durability of the rules")
+	}
+}
+
+func TestLegacyGameRules(t *testing.T) {
+	defenition, err := getDefenitionFromDbDefenition(repositories.GetGameBoardDefenitionResult{
+		PlayerIds:   []string{"red", "blue"},
+		XMax:        2,
+		YMax:        2,
+		TargetScore: 6,
+	})
+	if err != nil {
+		t.Fatalf("Failed to read defenition: %v", err)
+	}
+
+	standardRules := getStandardRules()
+	if defenition.PawnDurability != standardRules.PawnDurability || defenition.ShufflesPerTurn != standardRules.ShufflesPerTurn {
+		t.Errorf("The game stored without rules does not use the standard ones")
+	}
+}
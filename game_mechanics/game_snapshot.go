@@ -0,0 +1,156 @@
+package gamemechanics
+
+import (
+	"errors"
+	"projectdeflector/game/repositories"
+)
+
+// a snapshot of the processed game board is stored every SNAPSHOT_INTERVAL events
+const SNAPSHOT_INTERVAL = 20
+
+// bump SNAPSHOT_VERSION whenever the processed state gains or changes a field,
+// the stored snapshots with another version are ignored and the game is fully replayed
+const SNAPSHOT_VERSION = 7
+
+func shouldSnapshot(previousEventCount int, eventCount int) bool {
+	return previousEventCount/SNAPSHOT_INTERVAL != eventCount/SNAPSHOT_INTERVAL
+}
+
+func toSnapshotPawn(pawn Pawn) repositories.SnapshotPawn {
+	snapshotPawn := repositories.SnapshotPawn{
+		X:           pawn.Position.X,
+		Y:           pawn.Position.Y,
+		Name:        pawn.Name,
+		TurnPlaced:  pawn.TurnPlaced,
+		Durability:  pawn.Durability,
+		PlayerOwner: pawn.PlayerOwner,
+	}
+	if pawn.Twin != nil {
+		snapshotPawn.Twin = &repositories.SnapshotPosition{X: pawn.Twin.X, Y: pawn.Twin.Y}
+	}
+	return snapshotPawn
+}
+
+func fromSnapshotPawn(snapshotPawn repositories.SnapshotPawn) Pawn {
+	pawn := Pawn{
+		Position:    position(snapshotPawn.X, snapshotPawn.Y),
+		Name:        snapshotPawn.Name,
+		TurnPlaced:  snapshotPawn.TurnPlaced,
+		Durability:  snapshotPawn.Durability,
+		PlayerOwner: snapshotPawn.PlayerOwner,
+	}
+	if snapshotPawn.Twin != nil {
+		twin := position(snapshotPawn.Twin.X, snapshotPawn.Twin.Y)
+		pawn.Twin = &twin
+	}
+	return pawn
+}
+
+func (processedGameBoard ProcessedGameBoard) toSnapshot() repositories.GameSnapshot {
+	cloned := processedGameBoard.clone()
+
+	pawns := make([]repositories.SnapshotPawn, 0)
+	for _, row := range cloned.GameBoard.Pawns {
+		for _, pawn := range row {
+			if pawn == nil {
+				continue
+			}
+			pawns = append(pawns, toSnapshotPawn(*pawn))
+		}
+	}
+
+	placements := make([]repositories.SnapshotPlacement, 0)
+	for _, placement := range cloned.UndoablePlacements {
+		snapshotPlacement := repositories.SnapshotPlacement{
+			X: placement.Position.X,
+			Y: placement.Position.Y,
+		}
+		if placement.ReplacedPawn != nil {
+			replacedPawn := toSnapshotPawn(*placement.ReplacedPawn)
+			snapshotPlacement.ReplacedPawn = &replacedPawn
+		}
+		placements = append(placements, snapshotPlacement)
+	}
+
+	return repositories.GameSnapshot{
+		Version:             SNAPSHOT_VERSION,
+		EventCount:          len(cloned.GameBoard.defenition.Events),
+		Turn:                cloned.GameBoard.Turn,
+		Pawns:               pawns,
+		ScoreBoard:          cloned.GameBoard.ScoreBoard,
+		PawnVariants:        cloned.PawnVariants,
+		AvailableShuffles:   cloned.AvailableShuffles,
+		PlayersInMatchPoint: cloned.PlayersInMatchPoint,
+		GameInProgress:      cloned.GameInProgress,
+		Winner:              cloned.Winner,
+		LastTurnEndTime:     cloned.LastTurnEndTime,
+		AvailableUndos:      cloned.AvailableUndos,
+		UndoablePlacements:  placements,
+		DrawOfferedBy:       cloned.DrawOfferedBy,
+		IsDraw:              cloned.IsDraw,
+		ConsecutiveTimeouts: cloned.ConsecutiveTimeouts,
+		EndReason:           cloned.EndReason,
+		Clocks:              cloned.Clocks,
+		EliminatedPlayers:   cloned.EliminatedPlayers,
+	}
+}
+
+// NewGameBoardFromSnapshot restores the snapshot then only processes the events of the defenition that come after it
+func NewGameBoardFromSnapshot(defenition GameBoardDefenition, snapshot repositories.GameSnapshot) (ProcessedGameBoard, error) {
+	if snapshot.Version != SNAPSHOT_VERSION {
+		return ProcessedGameBoard{}, errors.New("unsupported snapshot version")
+	}
+	if snapshot.EventCount > len(defenition.Events) {
+		return ProcessedGameBoard{}, errors.New("snapshot is ahead of the game events")
+	}
+
+	varianceFactory, err := getVarianceFactory(defenition)
+	if err != nil {
+		return ProcessedGameBoard{}, err
+	}
+
+	events := defenition.Events
+	gameBoardInProcess := newEmptyGameBoard(defenition, varianceFactory)
+	gameBoardInProcess.GameBoard.defenition.Events = append(make([]GameEvent, 0), events[:snapshot.EventCount]...)
+
+	for _, snapshotPawn := range snapshot.Pawns {
+		pawns, err := addPawn(gameBoardInProcess.GameBoard.Pawns, fromSnapshotPawn(snapshotPawn))
+		if err != nil {
+			return ProcessedGameBoard{}, err
+		}
+		gameBoardInProcess.GameBoard.Pawns = pawns
+	}
+
+	gameBoardInProcess.GameBoard.Turn = snapshot.Turn
+	gameBoardInProcess.GameInProgress = snapshot.GameInProgress
+	gameBoardInProcess.Winner = snapshot.Winner
+	gameBoardInProcess.LastTurnEndTime = snapshot.LastTurnEndTime
+	gameBoardInProcess.DrawOfferedBy = snapshot.DrawOfferedBy
+	gameBoardInProcess.IsDraw = snapshot.IsDraw
+	gameBoardInProcess.EndReason = snapshot.EndReason
+	for _, side := range getSides(defenition) {
+		gameBoardInProcess.GameBoard.ScoreBoard[side] = snapshot.ScoreBoard[side]
+		gameBoardInProcess.PlayersInMatchPoint[side] = snapshot.PlayersInMatchPoint[side]
+	}
+	for _, playerId := range defenition.PlayerIds {
+		gameBoardInProcess.PawnVariants[playerId] = append([]string{}, snapshot.PawnVariants[playerId]...)
+		gameBoardInProcess.AvailableShuffles[playerId] = snapshot.AvailableShuffles[playerId]
+		gameBoardInProcess.AvailableUndos[playerId] = snapshot.AvailableUndos[playerId]
+		gameBoardInProcess.ConsecutiveTimeouts[playerId] = snapshot.ConsecutiveTimeouts[playerId]
+		gameBoardInProcess.Clocks[playerId] = snapshot.Clocks[playerId]
+		gameBoardInProcess.EliminatedPlayers[playerId] = snapshot.EliminatedPlayers[playerId]
+	}
+
+	for _, snapshotPlacement := range snapshot.UndoablePlacements {
+		placement := PawnPlacement{
+			Position: position(snapshotPlacement.X, snapshotPlacement.Y),
+		}
+		if snapshotPlacement.ReplacedPawn != nil {
+			replacedPawn := fromSnapshotPawn(*snapshotPlacement.ReplacedPawn)
+			placement.ReplacedPawn = &replacedPawn
+		}
+		gameBoardInProcess.UndoablePlacements = append(gameBoardInProcess.UndoablePlacements, placement)
+	}
+
+	return ProcessEvents(gameBoardInProcess, events[snapshot.EventCount:])
+}
@@ -0,0 +1,101 @@
+package gamemechanics
+
+import (
+	"reflect"
+	"testing"
+)
+
+// the last deflections are not part of the snapshot, they are recomputed by the next fire
+func getComparableMap(processedGameBoard ProcessedGameBoard) map[string]interface{} {
+	toMap := processedGameBoard.toMap()
+	delete(toMap, "deflections")
+	toMap["eventCount"] = len(processedGameBoard.GameBoard.defenition.Events)
+	toMap["gameInProgress"] = processedGameBoard.GameInProgress
+	toMap["winner"] = processedGameBoard.Winner
+	toMap["undoablePlacements"] = append([]PawnPlacement{}, processedGameBoard.UndoablePlacements...)
+	return toMap
+}
+
+func TestSnapshotMatchesFullReplay(t *testing.T) {
+	defenition := newReplayDefenition("62a0c1f2e4b0a1b2c3d4e5f6")
+	defenition.VarianceVersion = CURRENT_VARIANCE_VERSION
+
+	fullReplay, err := NewGameBoard(defenition)
+	if err != nil {
+		t.Fatalf("Failed to replay game: %v", err)
+	}
+	expected := getComparableMap(fullReplay)
+
+	for eventCount := 0; eventCount <= len(defenition.Events); eventCount++ {
+		partialDefenition := defenition
+		partialDefenition.Events = defenition.Events[:eventCount]
+		partialReplay, err := NewGameBoard(partialDefenition)
+		if err != nil {
+			t.Fatalf("Failed to replay %d events: %v", eventCount, err)
+		}
+
+		snapshot := partialReplay.toSnapshot()
+		if snapshot.EventCount != eventCount {
+			t.Errorf("Snapshot of %d events has the event count %d", eventCount, snapshot.EventCount)
+		}
+
+		restored, err := NewGameBoardFromSnapshot(defenition, snapshot)
+		if err != nil {
+			t.Fatalf("Failed to restore the snapshot of %d events: %v", eventCount, err)
+		}
+		if !reflect.DeepEqual(getComparableMap(restored), expected) {
+			t.Errorf("Restoring the snapshot of %d events differs from the full replay", eventCount)
+		}
+	}
+}
+
+func TestInvalidSnapshotIsRejected(t *testing.T) {
+	defenition := newReplayDefenition("62a0c1f2e4b0a1b2c3d4e5f6")
+	processedGameBoard, err := NewGameBoard(defenition)
+	if err != nil {
+		t.Fatalf("Failed to replay game: %v", err)
+	}
+
+	snapshot := processedGameBoard.toSnapshot()
+	snapshot.EventCount += 1
+	if _, err := NewGameBoardFromSnapshot(defenition, snapshot); err == nil {
+		t.Errorf("Restored a snapshot that is ahead of the events")
+	}
+
+	snapshot = processedGameBoard.toSnapshot()
+	snapshot.Version = SNAPSHOT_VERSION + 1
+	if _, err := NewGameBoardFromSnapshot(defenition, snapshot); err == nil {
+		t.Errorf("Restored a snapshot with an unknown version")
+	}
+}
+
+func TestStoredSnapshotMatchesFullReplay(t *testing.T) {
+	useCase, gameId := newTestGame(t, "red", "blue")
+	playTestTurns(t, useCase, gameId, 30)
+
+	repoDefenition, err := useCase.Repo.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if len(repoDefenition.Events) < SNAPSHOT_INTERVAL {
+		t.Fatalf("The game ended after %d events, before the first snapshot", len(repoDefenition.Events))
+	}
+	if repoDefenition.Snapshot == nil || repoDefenition.Snapshot.EventCount < SNAPSHOT_INTERVAL {
+		t.Fatalf("No snapshot was stored after %d events", len(repoDefenition.Events))
+	}
+
+	fromSnapshot, err := getGameBoardFromDbDefenition(repoDefenition)
+	if err != nil {
+		t.Fatalf("Failed to load game from the snapshot: %v", err)
+	}
+
+	repoDefenition.Snapshot = nil
+	fullReplay, err := getGameBoardFromDbDefenition(repoDefenition)
+	if err != nil {
+		t.Fatalf("Failed to replay game: %v", err)
+	}
+
+	if !reflect.DeepEqual(getComparableMap(fromSnapshot), getComparableMap(fullReplay)) {
+		t.Errorf("Loading from the snapshot differs from the full replay")
+	}
+}
@@ -3,6 +3,13 @@ package gamemechanics
 const (
 	SLASH     = "slash"
 	BACKSLASH = "backslash"
+	// BLOCKER absorbs the deflector, nobody scores from it
+	BLOCKER = "blocker"
+	// SPLITTER sends the deflector out in both perpendicular directions,
+	// it goes on like it would off a slash and branches off like it would off a backslash
+	SPLITTER = "splitter"
+	// PORTAL sends the deflector out of its twin in the same direction, a portal without a twin lets it through
+	PORTAL = "portal"
 )
 
 type Pawn struct {
@@ -11,6 +18,8 @@ type Pawn struct {
 	TurnPlaced  int
 	Durability  int
 	PlayerOwner string
+	// Twin is the position of the portal this one is linked to
+	Twin *Position
 }
 
 func (pawn Pawn) getDeflectedDirection(currentDirection int) int {
@@ -33,18 +42,39 @@ func (pawn Pawn) getDeflectedDirection(currentDirection int) int {
 		return backslashDeflection[currentDirection]
 	}
 
-	if pawn.Name == SLASH {
+	if pawn.Name == SLASH || pawn.Name == SPLITTER {
 		return slashDeflection[currentDirection]
 	}
+
+	if pawn.Name == BLOCKER {
+		return BLOCKED
+	}
 	return currentDirection
 }
 
+// getBranchDirection is the direction of the beam a splitter branches off
+func (pawn Pawn) getBranchDirection(currentDirection int) (int, bool) {
+	if pawn.Name != SPLITTER {
+		return 0, false
+	}
+
+	backslashPawn := pawn
+	backslashPawn.Name = BACKSLASH
+	return backslashPawn.getDeflectedDirection(currentDirection), true
+}
+
 func (pawn Pawn) toMap() map[string]interface{} {
+	var twin map[string]interface{}
+	if pawn.Twin != nil {
+		twin = pawn.Twin.toMap()
+	}
+
 	return map[string]interface{}{
 		"position":    pawn.Position.toMap(),
 		"name":        pawn.Name,
 		"turnPlaced":  pawn.TurnPlaced,
 		"durability":  pawn.Durability,
 		"playerOwner": pawn.PlayerOwner,
+		"twin":        twin,
 	}
 }
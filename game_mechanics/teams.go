@@ -0,0 +1,133 @@
+package gamemechanics
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	TEAM_COUNT = 2
+	TEAM_SIZE  = 2
+)
+
+// Team members share a goal edge and a score, they take turns alternately with the other team
+// and win or lose together
+type Team struct {
+	Id        string
+	PlayerIds []string
+}
+
+func (team Team) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":        team.Id,
+		"playerIds": team.PlayerIds,
+	}
+}
+
+// NewTeams names the teams after their order, a team id can never be a player id
+func NewTeams(teamPlayerIds [][]string) ([]Team, error) {
+	if len(teamPlayerIds) != TEAM_COUNT {
+		return []Team{}, fmt.Errorf("a team game can only have %d teams", TEAM_COUNT)
+	}
+
+	teams := make([]Team, 0)
+	for i, playerIds := range teamPlayerIds {
+		if len(playerIds) != TEAM_SIZE {
+			return []Team{}, fmt.Errorf("a team can only have %d players", TEAM_SIZE)
+		}
+		teams = append(teams, Team{
+			Id:        fmt.Sprintf("team:%d", i+1),
+			PlayerIds: playerIds,
+		})
+	}
+	return teams, nil
+}
+
+// getTurnOrder alternates the players of the teams
+func getTurnOrder(teams []Team) []string {
+	playerIds := make([]string, 0)
+	for i := 0; i < TEAM_SIZE; i++ {
+		for _, team := range teams {
+			if i < len(team.PlayerIds) {
+				playerIds = append(playerIds, team.PlayerIds[i])
+			}
+		}
+	}
+	return playerIds
+}
+
+func checkUniquePlayers(playerIds []string) error {
+	for i, playerId := range playerIds {
+		for _, otherPlayerId := range playerIds[i+1:] {
+			if playerId == otherPlayerId {
+				return errors.New("a player can only join a game once")
+			}
+		}
+	}
+	return nil
+}
+
+func isTeamGame(defenition GameBoardDefenition) bool {
+	return len(defenition.Teams) > 0
+}
+
+// getSides own the goal edges and the scores and are the winners of the games,
+// they are the teams of a team game and the players otherwise
+func getSides(defenition GameBoardDefenition) []string {
+	if !isTeamGame(defenition) {
+		return defenition.PlayerIds
+	}
+
+	sides := make([]string, 0)
+	for _, team := range defenition.Teams {
+		sides = append(sides, team.Id)
+	}
+	return sides
+}
+
+func getPlayerSide(defenition GameBoardDefenition, playerId string) string {
+	for _, team := range defenition.Teams {
+		for _, teamPlayerId := range team.PlayerIds {
+			if teamPlayerId == playerId {
+				return team.Id
+			}
+		}
+	}
+	return playerId
+}
+
+func getSidePlayers(defenition GameBoardDefenition, side string) []string {
+	for _, team := range defenition.Teams {
+		if team.Id == side {
+			return team.PlayerIds
+		}
+	}
+	if isPlayer(defenition, side) {
+		return []string{side}
+	}
+	return []string{}
+}
+
+// getActiveSides are the sides with at least one player that was not eliminated
+func getActiveSides(gameBoardInProcess ProcessedGameBoard) []string {
+	defenition := gameBoardInProcess.GameBoard.defenition
+	activeSides := make([]string, 0)
+	for _, side := range getSides(defenition) {
+		for _, playerId := range getSidePlayers(defenition, side) {
+			if !gameBoardInProcess.EliminatedPlayers[playerId] {
+				activeSides = append(activeSides, side)
+				break
+			}
+		}
+	}
+	return activeSides
+}
+
+func isActiveSide(gameBoardInProcess ProcessedGameBoard, side string) bool {
+	for _, activeSide := range getActiveSides(gameBoardInProcess) {
+		if activeSide == side {
+			return true
+		}
+	}
+	return false
+}
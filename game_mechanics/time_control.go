@@ -0,0 +1,76 @@
+package gamemechanics
+
+// TimeControl gives every player a bank of time for the whole game instead of a flat time per turn.
+// After each turn the time it took is taken from the bank of the player, minus the Delay that is free,
+// then the Increment is added. All the durations are in milliseconds, a game without a Bank uses TimePerTurn
+type TimeControl struct {
+	Bank      int64
+	Increment int64
+	Delay     int64
+}
+
+func (timeControl TimeControl) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"bank":      timeControl.Bank,
+		"increment": timeControl.Increment,
+		"delay":     timeControl.Delay,
+	}
+}
+
+func (timeControl TimeControl) isEnabled() bool {
+	return timeControl.Bank > 0
+}
+
+// getTurnTimeLimit is the time the current player has to end the turn, counted from LastTurnEndTime
+func (processedGameBoard ProcessedGameBoard) getTurnTimeLimit() int64 {
+	defenition := processedGameBoard.GameBoard.defenition
+	if !defenition.TimeControl.isEnabled() {
+		return int64(defenition.TimePerTurn)
+	}
+
+	playerId := GetPlayerTurn(processedGameBoard.GameBoard)
+	return defenition.TimeControl.Delay + processedGameBoard.Clocks[playerId]
+}
+
+func (processedGameBoard ProcessedGameBoard) copyClocks() map[string]int64 {
+	clocks := make(map[string]int64)
+	for key, value := range processedGameBoard.Clocks {
+		clocks[key] = value
+	}
+	return clocks
+}
+
+// chargeTurn takes the time a turn took from the clock of the player,
+// a clock that ran out stays at 0 and does not get the increment
+func (processedGameBoard ProcessedGameBoard) chargeTurn(playerId string, endTime int64) {
+	timeControl := processedGameBoard.GameBoard.defenition.TimeControl
+	if !timeControl.isEnabled() {
+		return
+	}
+
+	spent := endTime - processedGameBoard.LastTurnEndTime - timeControl.Delay
+	if spent < 0 {
+		spent = 0
+	}
+
+	clock := processedGameBoard.Clocks[playerId] - spent
+	if clock <= 0 {
+		processedGameBoard.Clocks[playerId] = 0
+		return
+	}
+	processedGameBoard.Clocks[playerId] = clock + timeControl.Increment
+}
+
+// GetFlagFallEvents makes a player whose clock ran out lose
+func GetFlagFallEvents(gameBoardInProcess ProcessedGameBoard) []GameEvent {
+	if !gameBoardInProcess.GameBoard.defenition.TimeControl.isEnabled() {
+		return []GameEvent{}
+	}
+
+	for _, playerId := range getActivePlayers(gameBoardInProcess) {
+		if gameBoardInProcess.Clocks[playerId] <= 0 {
+			return getLossEvents(gameBoardInProcess, playerId, END_REASON_FLAG_FALL)
+		}
+	}
+	return []GameEvent{}
+}
@@ -0,0 +1,122 @@
+package gamemechanics
+
+import (
+	"testing"
+	"time"
+)
+
+func newTimeControlDefenition() GameBoardDefenition {
+	defenition := NewGameBoardDefinition("", []string{"red", "blue"})
+	defenition.StartTime = time.Now().UnixMilli()
+	defenition.TimeControl = TimeControl{
+		Bank:      10000,
+		Increment: 2000,
+		Delay:     1000,
+	}
+	return defenition
+}
+
+func TestTimeControlClocks(t *testing.T) {
+	defenition := newTimeControlDefenition()
+	start := defenition.StartTime
+	defenition.Events = []GameEvent{
+		// red plays inside the delay, the clock only gets the increment
+		EndTurnEvent{name: END_TURN, playerOwner: "red", endTime: start + 500},
+		// blue spends 4s, 3s are taken from the bank
+		EndTurnEvent{name: END_TURN, playerOwner: "blue", endTime: start + 4500},
+	}
+
+	processedGameBoard, err := NewGameBoard(defenition)
+	if err != nil {
+		t.Fatalf("Failed to process game: %v", err)
+	}
+
+	if processedGameBoard.Clocks["red"] != 12000 {
+		t.Errorf("Expected red to have 12000ms left, got %d", processedGameBoard.Clocks["red"])
+	}
+	if processedGameBoard.Clocks["blue"] != 9000 {
+		t.Errorf("Expected blue to have 9000ms left, got %d", processedGameBoard.Clocks["blue"])
+	}
+
+	// the deadline of red is its clock plus the delay
+	if processedGameBoard.getTurnDeadline() != start+4500+1000+12000 {
+		t.Errorf("Wrong turn deadline %d", processedGameBoard.getTurnDeadline())
+	}
+}
+
+func TestFlagFall(t *testing.T) {
+	useCase := newTestUseCase(t)
+
+	defenition := newTimeControlDefenition()
+	defenition.TimeControl = TimeControl{Bank: 1}
+	// the bank of red ran out long ago
+	defenition.StartTime = time.Now().Add(-time.Minute).UnixMilli()
+	gameId, err := useCase.Repo.InsertGame(getInsertDefenition(defenition))
+	if err != nil {
+		t.Fatalf("Failed to insert game: %v", err)
+	}
+
+	result, err := useCase.ExpireTurn(gameId, "system", 0)
+	if err != nil {
+		t.Fatalf("Failed to expire turn: %v", err)
+	}
+	if result.Winner != "blue" || result.EndReason != END_REASON_FLAG_FALL {
+		t.Errorf("Expected blue to win on time, got %s by %s", result.Winner, result.EndReason)
+	}
+	if result.Clocks["red"] != 0 {
+		t.Errorf("Expected the clock of red to have run out, got %d", result.Clocks["red"])
+	}
+
+	game, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if game.ToMap()["endReason"] != END_REASON_FLAG_FALL || game.ProcessedGameBoard.GameInProgress {
+		t.Errorf("The flag fall was not stored")
+	}
+}
+
+func TestForkOfAnOldGameStartsANewTurn(t *testing.T) {
+	useCase := newTestUseCase(t)
+
+	defenition := newTimeControlDefenition()
+	defenition.StartTime = time.Now().Add(-time.Hour).UnixMilli()
+	start := defenition.StartTime
+	defenition.Events = []GameEvent{
+		EndTurnEvent{name: END_TURN, playerOwner: "red", endTime: start + 500},
+		EndTurnEvent{name: END_TURN, playerOwner: "blue", endTime: start + 4500},
+	}
+	gameId, err := useCase.Repo.InsertGame(getInsertDefenition(defenition))
+	if err != nil {
+		t.Fatalf("Failed to insert game: %v", err)
+	}
+
+	forkId, err := useCase.ForkGame(gameId, 2)
+	if err != nil {
+		t.Fatalf("Failed to fork: %v", err)
+	}
+	fork, err := useCase.GetGame(forkId)
+	if err != nil {
+		t.Fatalf("Failed to get fork: %v", err)
+	}
+	if fork.ProcessedGameBoard.Clocks["red"] != 12000 || fork.ProcessedGameBoard.Clocks["blue"] != 9000 {
+		t.Errorf("The fork does not keep the clocks of the original game %v", fork.ProcessedGameBoard.Clocks)
+	}
+
+	// the turn of red ran out an hour ago in the original game but not in the fork
+	if _, err := useCase.ExpireTurn(forkId, "system", 2); err == nil {
+		t.Fatalf("The turn of the fork ran out with the turn of the original game")
+	}
+
+	position, _ := getEmptyPosition(fork.ProcessedGameBoard.GameBoard)
+	if _, err := useCase.AddPawn(forkId, AddPawnRequest{X: position.X, Y: position.Y, PlayerSide: "red"}); err != nil {
+		t.Fatalf("Failed to add pawn: %v", err)
+	}
+	result, err := useCase.EndTurn(forkId, "red", nil)
+	if err != nil {
+		t.Fatalf("Failed to end turn: %v", err)
+	}
+	if result.Winner != "" || result.Clocks["red"] != 14000 {
+		t.Errorf("Expected red to play in time, got the clock %d and the winner %s", result.Clocks["red"], result.Winner)
+	}
+}
@@ -0,0 +1,38 @@
+package gamemechanics
+
+import (
+	"errors"
+	"log"
+	"projectdeflector/game/repositories"
+	"time"
+)
+
+// ExpireTurns ends the turns that ran out of time in the ongoing games and returns how many were ended.
+// Several servers can run it at the same time, a game that is locked is skipped and retried on the next run
+// instead of holding up the other games, and a turn is only ended if the game still has the events it had when it was found
+func (useCase UseCase) ExpireTurns() (int, error) {
+	games, err := useCase.Repo.GetExpiredTurnGames(time.Now().UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, game := range games {
+		_, err := useCase.expireTurn(game.Id, game.EventCount, repositories.TryLockGame)
+		if err == nil {
+			expired += 1
+		} else if !isExpiryConflict(err) {
+			log.Printf("could not expire the turn of game %s: %v", game.Id, err)
+		}
+	}
+	return expired, nil
+}
+
+// isExpiryConflict is true when another request got to the game first
+func isExpiryConflict(err error) bool {
+	var conflictError EventCountConflictError
+	return errors.Is(err, repositories.ErrGameLocked) ||
+		errors.Is(err, repositories.ErrEventCountMismatch) ||
+		errors.Is(err, ErrTurnAlreadyEnded) ||
+		errors.As(err, &conflictError)
+}
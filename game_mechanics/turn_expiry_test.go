@@ -0,0 +1,181 @@
+package gamemechanics
+
+import (
+	"projectdeflector/game/repositories"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExpireTurns(t *testing.T) {
+	useCase := newTestUseCase(t)
+
+	ongoingId, err := useCase.CreateNewGame([]string{"red", "blue"}, getStandardRules())
+	if err != nil {
+		t.Fatalf("Failed to create game: %v", err)
+	}
+
+	defenition := NewGameBoardDefinition("", []string{"red", "blue"})
+	defenition.StartTime = time.Now().Add(-time.Minute).UnixMilli()
+	expiredId, err := useCase.Repo.InsertGame(getInsertDefenition(defenition))
+	if err != nil {
+		t.Fatalf("Failed to insert game: %v", err)
+	}
+
+	// several servers expire the turns at the same time
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	expired := 0
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, err := useCase.ExpireTurns()
+			if err != nil {
+				t.Errorf("Failed to expire turns: %v", err)
+			}
+			mutex.Lock()
+			expired += count
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if expired != 1 {
+		t.Errorf("Expected a single turn to expire, %d did", expired)
+	}
+
+	game, err := useCase.GetGame(expiredId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if game.ProcessedGameBoard.GameBoard.Turn != 1 {
+		t.Errorf("Expected the turn to have ended, the game is at turn %d", game.ProcessedGameBoard.GameBoard.Turn)
+	}
+
+	game, err = useCase.GetGame(ongoingId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if game.EventCount != 0 {
+		t.Errorf("A turn that did not run out was ended")
+	}
+
+	// the next turn has a new deadline
+	expired, err = useCase.ExpireTurns()
+	if err != nil || expired != 0 {
+		t.Errorf("Expected no turn to expire, %d did (%v)", expired, err)
+	}
+}
+
+func TestExpireTurnsSkipsLockedGames(t *testing.T) {
+	useCase := newTestUseCase(t)
+
+	defenition := NewGameBoardDefinition("", []string{"red", "blue"})
+	defenition.StartTime = time.Now().Add(-time.Minute).UnixMilli()
+	gameId, err := useCase.Repo.InsertGame(getInsertDefenition(defenition))
+	if err != nil {
+		t.Fatalf("Failed to insert game: %v", err)
+	}
+
+	// another request holds the game
+	_, unlock, err := repositories.LockGame(useCase.Repo, gameId)
+	if err != nil {
+		t.Fatalf("Failed to lock game: %v", err)
+	}
+
+	start := time.Now()
+	expired, err := useCase.ExpireTurns()
+	if err != nil || expired != 0 {
+		t.Errorf("Expected the locked game to be skipped, %d turns expired (%v)", expired, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Waited %v for the locked game", time.Since(start))
+	}
+
+	// the next run gets to it
+	unlock()
+	expired, err = useCase.ExpireTurns()
+	if err != nil || expired != 1 {
+		t.Errorf("Expected the turn to expire once the game is unlocked, %d did (%v)", expired, err)
+	}
+}
+
+func TestForfeitAfterRepeatedTimeouts(t *testing.T) {
+	useCase := newTestUseCase(t)
+
+	start := time.Now().Add(-time.Hour).UnixMilli()
+	// expireTurn stores a game that went through the events an hour ago, then lets the turn that followed run out
+	expireTurn := func(events ...GameEvent) (string, EndTurnResult) {
+		defenition := NewGameBoardDefinition("", []string{"red", "blue"})
+		defenition.StartTime = start
+		defenition.TimePerTurn = 1000
+		defenition.MaxTimeouts = 2
+		defenition.Events = events
+		gameId, err := useCase.Repo.InsertGame(getInsertDefenition(defenition))
+		if err != nil {
+			t.Fatalf("Failed to insert game: %v", err)
+		}
+		result, err := useCase.ExpireTurn(gameId, "system", len(events))
+		if err != nil {
+			t.Fatalf("Failed to expire turn: %v", err)
+		}
+		return gameId, result
+	}
+
+	// ending a turn in time resets the count of the player
+	turnsInTime := []GameEvent{
+		EndTurnEvent{name: END_TURN, playerOwner: "system", endTime: start + 2000},
+		EndTurnEvent{name: END_TURN, playerOwner: "system", endTime: start + 4000},
+		EndTurnEvent{name: END_TURN, playerOwner: "red", endTime: start + 4500},
+		EndTurnEvent{name: END_TURN, playerOwner: "blue", endTime: start + 5000},
+	}
+	_, result := expireTurn(turnsInTime...)
+	if result.Winner != "" {
+		t.Fatalf("The game ended after red let a single turn run out")
+	}
+
+	gameId, result := expireTurn(append(turnsInTime,
+		EndTurnEvent{name: END_TURN, playerOwner: "system", endTime: start + 7000},
+		EndTurnEvent{name: END_TURN, playerOwner: "system", endTime: start + 9000},
+	)...)
+	if result.Winner != "blue" || result.EndReason != END_REASON_FORFEIT {
+		t.Errorf("Expected blue to win by forfeit, got %s by %s", result.Winner, result.EndReason)
+	}
+
+	game, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if game.ToMap()["endReason"] != END_REASON_FORFEIT || game.ProcessedGameBoard.GameInProgress {
+		t.Errorf("The forfeit was not stored")
+	}
+}
+
+func TestForfeitEliminatesWhileMorePlayersAreLeft(t *testing.T) {
+	defenition := NewGameBoardDefinition("", []string{"red", "blue", "green"})
+	processedGameBoard, err := NewGameBoard(defenition)
+	if err != nil {
+		t.Fatalf("Failed to create game: %v", err)
+	}
+
+	processedGameBoard.ConsecutiveTimeouts["blue"] = defenition.MaxTimeouts
+	events := GetForfeitEvents(processedGameBoard)
+	if len(events) != 1 || events[0].(EliminatePlayerEvent).playerOwner != "blue" {
+		t.Fatalf("Expected blue to be eliminated, got %v", events)
+	}
+
+	processedGameBoard, err = ProcessEvents(processedGameBoard, events)
+	if err != nil {
+		t.Fatalf("Failed to eliminate: %v", err)
+	}
+	if !processedGameBoard.GameInProgress || !processedGameBoard.EliminatedPlayers["blue"] {
+		t.Errorf("Blue was not eliminated")
+	}
+
+	processedGameBoard.ConsecutiveTimeouts["green"] = defenition.MaxTimeouts
+	events = GetForfeitEvents(processedGameBoard)
+	if len(events) != 1 || events[0].(WinEvent).playerOwner != "red" {
+		t.Errorf("Expected red to win, got %v", events)
+	}
+}
@@ -2,49 +2,193 @@ package gamemechanics
 
 import (
 	"errors"
+	"fmt"
 	"projectdeflector/game/network"
 	"projectdeflector/game/repositories"
+	"time"
 )
 
 type UseCase struct {
 	Repo repositories.Repository
 }
 
-func getInsertDefenition(defenition GameBoardDefenition) repositories.InserGameBoardDefenition {
+// ErrTurnAlreadyEnded is returned when a turn that ran out was ended by someone else in the meantime
+var ErrTurnAlreadyEnded = errors.New("turn already ended")
+
+// EventCountConflictError is returned when a client acts on a game state that it has not seen yet
+type EventCountConflictError struct {
+	ExpectedEventCount int
+	EventCount         int
+}
+
+func (err EventCountConflictError) Error() string {
+	return fmt.Sprintf("the game has %d events but %d were expected", err.EventCount, err.ExpectedEventCount)
+}
+
+func checkEventCount(processedGameBoard ProcessedGameBoard, expectedEventCount *int) error {
+	eventCount := len(processedGameBoard.GameBoard.defenition.Events)
+	if expectedEventCount != nil && *expectedEventCount != eventCount {
+		return EventCountConflictError{
+			ExpectedEventCount: *expectedEventCount,
+			EventCount:         eventCount,
+		}
+	}
+	return nil
+}
+
+func encodeEvents(events []GameEvent) []map[string]interface{} {
 	mappedEvents := make([]map[string]interface{}, 0)
-	for i := 0; i < len(defenition.Events); i++ {
-		mappedEvents = append(mappedEvents, defenition.Events[i].Encode())
+	for i := 0; i < len(events); i++ {
+		mappedEvents = append(mappedEvents, EncodeGameEvent(events[i]))
 	}
+	return mappedEvents
+}
 
+func getInsertDefenition(defenition GameBoardDefenition) repositories.InserGameBoardDefenition {
 	return repositories.InserGameBoardDefenition{
-		PlayerIds:   defenition.PlayerIds,
-		XMax:        defenition.XMax,
-		YMax:        defenition.YMax,
-		TargetScore: defenition.TargetScore,
-		Events:      mappedEvents,
-		TimePerTurn: defenition.TimePerTurn,
-		StartTime:   defenition.StartTime,
+		PlayerIds:       defenition.PlayerIds,
+		XMax:            defenition.XMax,
+		YMax:            defenition.YMax,
+		TargetScore:     defenition.TargetScore,
+		Events:          encodeEvents(defenition.Events),
+		TimePerTurn:     defenition.TimePerTurn,
+		PawnDurability:  defenition.PawnDurability,
+		ShufflesPerTurn: defenition.ShufflesPerTurn,
+		BlockerChance:   defenition.BlockerChance,
+		SplitterChance:  defenition.SplitterChance,
+		PortalChance:    defenition.PortalChance,
+		UndosPerTurn:    defenition.UndosPerTurn,
+		MaxTimeouts:     defenition.MaxTimeouts,
+		TimeControl: repositories.TimeControlDefenition{
+			Bank:      defenition.TimeControl.Bank,
+			Increment: defenition.TimeControl.Increment,
+			Delay:     defenition.TimeControl.Delay,
+		},
+		StartTime:       defenition.StartTime,
+		TurnDeadline:    getFirstTurnDeadline(defenition),
+		VarianceVersion: defenition.VarianceVersion,
+		Seed:            defenition.Seed,
+		ForkedFrom:      defenition.ForkedFrom,
+		ForkEventIndex:  defenition.ForkEventIndex,
+		Teams:           toTeamDefenitions(defenition.Teams),
+	}
+}
+
+func toTeamDefenitions(teams []Team) []repositories.TeamDefenition {
+	teamDefenitions := make([]repositories.TeamDefenition, 0)
+	for _, team := range teams {
+		teamDefenitions = append(teamDefenitions, repositories.TeamDefenition{
+			Id:        team.Id,
+			PlayerIds: team.PlayerIds,
+		})
+	}
+	return teamDefenitions
+}
+
+func fromTeamDefenitions(teamDefenitions []repositories.TeamDefenition) []Team {
+	teams := make([]Team, 0)
+	for _, teamDefenition := range teamDefenitions {
+		teams = append(teams, Team{
+			Id:        teamDefenition.Id,
+			PlayerIds: teamDefenition.PlayerIds,
+		})
+	}
+	return teams
+}
+
+func getFirstTurnDeadline(defenition GameBoardDefenition) int64 {
+	if defenition.TimeControl.isEnabled() {
+		return defenition.StartTime + defenition.TimeControl.Delay + defenition.TimeControl.Bank
 	}
+	return defenition.StartTime + int64(defenition.TimePerTurn)
 }
 
-func (useCase UseCase) CreateNewGame(playerIds []string) (string, error) {
+func (useCase UseCase) CreateNewGame(playerIds []string, rules GameRules) (string, error) {
+
+	if len(playerIds) < MIN_PLAYERS || len(playerIds) > MAX_PLAYERS {
+		return "", fmt.Errorf("a game can only have from %d to %d players", MIN_PLAYERS, MAX_PLAYERS)
+	}
+
+	return insertNewGame(useCase.Repo, NewGameBoardDefinitionWithRules("", playerIds, rules))
+}
+
+// CreateNewTeamGame starts a game between teams, the players of each team play alternately
+func (useCase UseCase) CreateNewTeamGame(teamPlayerIds [][]string, rules GameRules) (string, error) {
+	teams, err := NewTeams(teamPlayerIds)
+	if err != nil {
+		return "", err
+	}
+
+	defenition := NewGameBoardDefinitionWithRules("", getTurnOrder(teams), rules)
+	defenition.Teams = teams
+	return insertNewGame(useCase.Repo, defenition)
+}
+
+func insertNewGame(repo repositories.Repository, defenition GameBoardDefenition) (string, error) {
+	if err := checkUniquePlayers(defenition.PlayerIds); err != nil {
+		return "", err
+	}
+
+	if err := getGameRules(defenition).Validate(); err != nil {
+		return "", err
+	}
 
-	if len(playerIds) != 2 {
-		return "", errors.New("a game can only have two players")
+	seed, err := newGameSeed()
+	if err != nil {
+		return "", err
 	}
 
-	defenition := NewGameBoardDefinition("test", playerIds)
+	// the id is only known once the game is inserted
+	defenition.Seed = seed
 
 	insert := getInsertDefenition(defenition)
-	return useCase.Repo.InsertGame(insert)
+	return repo.InsertGame(insert)
+}
+
+// saveNewEvents only stores the events that were processed after the game was read,
+// it fails if another writer added events in the meantime
+func saveNewEvents(repo repositories.Repository, gameId string, processedGameBoard ProcessedGameBoard, previousEventCount int) error {
+	newEvents := processedGameBoard.GameBoard.defenition.Events[previousEventCount:]
+	appendDefenition := repositories.AppendGameEventsDefenition{
+		ExpectedEventCount: previousEventCount,
+		Events:             encodeEvents(newEvents),
+		Winner:             processedGameBoard.Winner,
+		Draw:               processedGameBoard.IsDraw,
+		EndReason:          processedGameBoard.EndReason,
+		TurnDeadline:       processedGameBoard.getTurnDeadline(),
+		EliminatedPlayers:  processedGameBoard.getEliminatedPlayers(),
+		WinningPlayers:     processedGameBoard.getWinningPlayers(),
+	}
+
+	if shouldSnapshot(previousEventCount, len(processedGameBoard.GameBoard.defenition.Events)) {
+		snapshot := processedGameBoard.toSnapshot()
+		appendDefenition.Snapshot = &snapshot
+	}
+	return repo.AppendGameEvents(gameId, appendDefenition)
+}
+
+// lockGameFunc takes the lock of a game, either waiting for it or giving up right away
+type lockGameFunc func(repo repositories.Repository, id string) (repositories.GetGameBoardDefenitionResult, func(), error)
+
+// getLockedProcessedGameBoard also returns the function that releases the lock,
+// which should be deferred as soon as the lock is taken and called again once the new events are saved,
+// so the game is not held while the players and the user service are notified
+func getLockedProcessedGameBoard(repo repositories.Repository, id string) (ProcessedGameBoard, func(), error) {
+	return lockProcessedGameBoard(repo, id, repositories.LockGame)
 }
 
-func getLockedProcessedGameBoard(repo repositories.Repository, id string) (ProcessedGameBoard, error) {
-	repoDefenition, err := repo.GetGameAndLock(id)
+func lockProcessedGameBoard(repo repositories.Repository, id string, lockGame lockGameFunc) (ProcessedGameBoard, func(), error) {
+	repoDefenition, unlock, err := lockGame(repo, id)
 	if err != nil {
-		return ProcessedGameBoard{}, err
+		return ProcessedGameBoard{}, unlock, err
 	}
-	return getGameBoardFromDbDefenition(repoDefenition)
+
+	processedGameBoard, err := getGameBoardFromDbDefenition(repoDefenition)
+	if err != nil {
+		unlock()
+		return ProcessedGameBoard{}, func() {}, err
+	}
+	return processedGameBoard, unlock, nil
 }
 
 func getProcessedGameBoard(repo repositories.Repository, id string) (ProcessedGameBoard, error) {
@@ -56,28 +200,69 @@ func getProcessedGameBoard(repo repositories.Repository, id string) (ProcessedGa
 }
 
 func getGameBoardFromDbDefenition(repoDefenition repositories.GetGameBoardDefenitionResult) (ProcessedGameBoard, error) {
+	defenition, err := getDefenitionFromDbDefenition(repoDefenition)
+	if err != nil {
+		return ProcessedGameBoard{}, err
+	}
+
+	snapshot := repoDefenition.Snapshot
+	if snapshot != nil && snapshot.Version == SNAPSHOT_VERSION {
+		return NewGameBoardFromSnapshot(defenition, *snapshot)
+	}
+	return NewGameBoard(defenition)
+}
+
+func getDefenitionFromDbDefenition(repoDefenition repositories.GetGameBoardDefenitionResult) (GameBoardDefenition, error) {
 	decodedEvents := make([]GameEvent, 0)
 	for i := 0; i < len(repoDefenition.Events); i++ {
 
 		event, err := DecodeGameEvent(repoDefenition.Events[i])
 		if err != nil {
-			return ProcessedGameBoard{}, err
+			return GameBoardDefenition{}, err
 		}
 		decodedEvents = append(decodedEvents, event)
 	}
 
+	// the games stored before the rules were configurable were played with the standard ones
+	standardRules := getStandardRules()
+	pawnDurability := standardRules.PawnDurability
+	if repoDefenition.PawnDurability != nil {
+		pawnDurability = *repoDefenition.PawnDurability
+	}
+	shufflesPerTurn := standardRules.ShufflesPerTurn
+	if repoDefenition.ShufflesPerTurn != nil {
+		shufflesPerTurn = *repoDefenition.ShufflesPerTurn
+	}
+
 	defenition := GameBoardDefenition{
-		Id:          repoDefenition.Id,
-		PlayerIds:   repoDefenition.PlayerIds,
-		Events:      decodedEvents,
-		YMax:        repoDefenition.YMax,
-		XMax:        repoDefenition.XMax,
-		TargetScore: repoDefenition.TargetScore,
-		StartTime:   repoDefenition.StartTime,
-		TimePerTurn: repoDefenition.TimePerTurn,
+		Id:              repoDefenition.Id,
+		PlayerIds:       repoDefenition.PlayerIds,
+		Events:          decodedEvents,
+		YMax:            repoDefenition.YMax,
+		XMax:            repoDefenition.XMax,
+		TargetScore:     repoDefenition.TargetScore,
+		StartTime:       repoDefenition.StartTime,
+		TimePerTurn:     repoDefenition.TimePerTurn,
+		PawnDurability:  pawnDurability,
+		ShufflesPerTurn: shufflesPerTurn,
+		BlockerChance:   repoDefenition.BlockerChance,
+		SplitterChance:  repoDefenition.SplitterChance,
+		PortalChance:    repoDefenition.PortalChance,
+		UndosPerTurn:    repoDefenition.UndosPerTurn,
+		MaxTimeouts:     repoDefenition.MaxTimeouts,
+		TimeControl: TimeControl{
+			Bank:      repoDefenition.TimeControl.Bank,
+			Increment: repoDefenition.TimeControl.Increment,
+			Delay:     repoDefenition.TimeControl.Delay,
+		},
+		VarianceVersion: repoDefenition.VarianceVersion,
+		Seed:            repoDefenition.Seed,
+		ForkedFrom:      repoDefenition.ForkedFrom,
+		ForkEventIndex:  repoDefenition.ForkEventIndex,
+		Teams:           fromTeamDefenitions(repoDefenition.Teams),
 	}
 
-	return NewGameBoard(defenition)
+	return defenition, nil
 }
 
 type GetGameResult struct {
@@ -103,14 +288,14 @@ func (useCase UseCase) GetGame(id string) (GetGameResult, error) {
 	if err != nil {
 		return GetGameResult{}, err
 	}
+	return getGameResult(processedGameBoard)
+}
+
+func getGameResult(processedGameBoard ProcessedGameBoard) (GetGameResult, error) {
 	eventCount := len(processedGameBoard.GameBoard.defenition.Events)
 
-	nextProcessedGameBoard, err := NewGameBoard(processedGameBoard.GameBoard.GetDefenition())
-	if err != nil {
-		return GetGameResult{}, err
-	}
 	fireEvent := NewFireDeflectorEvent()
-	nextProcessedGameBoard, err = ProcessEvents(nextProcessedGameBoard, []GameEvent{fireEvent})
+	nextProcessedGameBoard, err := ProcessEvents(processedGameBoard.clone(), []GameEvent{fireEvent})
 	if err != nil {
 		return GetGameResult{}, err
 	}
@@ -122,6 +307,201 @@ func (useCase UseCase) GetGame(id string) (GetGameResult, error) {
 	}, nil
 }
 
+// EventIndexOutOfRangeError is returned when asking for an event index that the game did not reach
+type EventIndexOutOfRangeError struct {
+	EventIndex int
+	EventCount int
+}
+
+func (err EventIndexOutOfRangeError) Error() string {
+	return fmt.Sprintf("event index %d is out of range, the game has %d events", err.EventIndex, err.EventCount)
+}
+
+// GetGameAt gives the game as it was after its first eventIndex events, with the same preview of the next deflection as GetGame
+func (useCase UseCase) GetGameAt(id string, eventIndex int) (GetGameResult, error) {
+	repoDefenition, err := useCase.Repo.GetGame(id)
+	if err != nil {
+		return GetGameResult{}, err
+	}
+
+	if eventIndex < 0 || eventIndex > len(repoDefenition.Events) {
+		return GetGameResult{}, EventIndexOutOfRangeError{
+			EventIndex: eventIndex,
+			EventCount: len(repoDefenition.Events),
+		}
+	}
+
+	repoDefenition.Events = repoDefenition.Events[:eventIndex]
+	if repoDefenition.Snapshot != nil && repoDefenition.Snapshot.EventCount > eventIndex {
+		repoDefenition.Snapshot = nil
+	}
+
+	processedGameBoard, err := getGameBoardFromDbDefenition(repoDefenition)
+	if err != nil {
+		return GetGameResult{}, err
+	}
+	return getGameResult(processedGameBoard)
+}
+
+// ForkGame copies the game as it was after its first eventIndex events into a new sandbox game.
+// The fork keeps the variance seed of the original game, so the same moves play out the same way,
+// and it is flagged so that it never counts for the stats of its players
+func (useCase UseCase) ForkGame(id string, eventIndex int) (string, error) {
+	repoDefenition, err := useCase.Repo.GetGame(id)
+	if err != nil {
+		return "", err
+	}
+
+	if eventIndex < 0 || eventIndex > len(repoDefenition.Events) {
+		return "", EventIndexOutOfRangeError{
+			EventIndex: eventIndex,
+			EventCount: len(repoDefenition.Events),
+		}
+	}
+
+	repoDefenition.Events = repoDefenition.Events[:eventIndex]
+	repoDefenition.Snapshot = nil
+	defenition, err := getDefenitionFromDbDefenition(repoDefenition)
+	if err != nil {
+		return "", err
+	}
+	defenition.Seed = getVarianceSeed(defenition)
+	defenition.ForkedFrom = id
+	defenition.ForkEventIndex = eventIndex
+
+	processedGameBoard, err := NewGameBoard(defenition)
+	if err != nil {
+		return "", err
+	}
+
+	// the turn of the fork starts now instead of running out with the turn of the original game
+	defenition = shiftTurnTimes(defenition, time.Now().UnixMilli()-processedGameBoard.LastTurnEndTime)
+	processedGameBoard, err = NewGameBoard(defenition)
+	if err != nil {
+		return "", err
+	}
+
+	insert := getInsertDefenition(defenition)
+	insert.Winner = processedGameBoard.Winner
+	insert.Draw = processedGameBoard.IsDraw
+	insert.EndReason = processedGameBoard.EndReason
+	insert.TurnDeadline = processedGameBoard.getTurnDeadline()
+	insert.EliminatedPlayers = processedGameBoard.getEliminatedPlayers()
+	insert.WinningPlayers = processedGameBoard.getWinningPlayers()
+	if shouldSnapshot(0, eventIndex) {
+		snapshot := processedGameBoard.toSnapshot()
+		insert.Snapshot = &snapshot
+	}
+	return useCase.Repo.InsertGame(insert)
+}
+
+// shiftTurnTimes moves the start and the end of every turn of a game by the offset,
+// the time each turn took stays the same
+func shiftTurnTimes(defenition GameBoardDefenition, offset int64) GameBoardDefenition {
+	defenition.StartTime += offset
+	shiftedEvents := make([]GameEvent, len(defenition.Events))
+	for i, event := range defenition.Events {
+		switch timedEvent := event.(type) {
+		case EndTurnEvent:
+			timedEvent.endTime += offset
+			shiftedEvents[i] = timedEvent
+		case EliminatePlayerEvent:
+			timedEvent.endTime += offset
+			shiftedEvents[i] = timedEvent
+		default:
+			shiftedEvents[i] = event
+		}
+	}
+	defenition.Events = shiftedEvents
+	return defenition
+}
+
+type GameReplayStep struct {
+	Event       GameEvent
+	GameBoard   GameBoard
+	Deflections []Deflection
+}
+
+func (step GameReplayStep) toMap() map[string]interface{} {
+	deflections := make([]map[string]interface{}, 0)
+	for i := 0; i < len(step.Deflections); i++ {
+		deflections = append(deflections, step.Deflections[i].toMap())
+	}
+
+	return map[string]interface{}{
+		"event":       EncodeGameEvent(step.Event),
+		"gameBoard":   step.GameBoard.toMap(),
+		"scoreBoard":  step.GameBoard.ScoreBoard,
+		"deflections": deflections,
+	}
+}
+
+type GameReplayResult struct {
+	GameId           string
+	PlayerIds        []string
+	InitialGameBoard GameBoard
+	Steps            []GameReplayStep
+}
+
+func (res GameReplayResult) ToMap() map[string]interface{} {
+	steps := make([]map[string]interface{}, 0)
+	for i := 0; i < len(res.Steps); i++ {
+		steps = append(steps, res.Steps[i].toMap())
+	}
+
+	return map[string]interface{}{
+		"gameId":           res.GameId,
+		"playerIds":        res.PlayerIds,
+		"initialGameBoard": res.InitialGameBoard.toMap(),
+		"steps":            steps,
+	}
+}
+
+// GetGameReplay processes the stored events one by one and keeps the game board after each of them
+func (useCase UseCase) GetGameReplay(id string) (GameReplayResult, error) {
+	repoDefenition, err := useCase.Repo.GetGame(id)
+	if err != nil {
+		return GameReplayResult{}, err
+	}
+
+	defenition, err := getDefenitionFromDbDefenition(repoDefenition)
+	if err != nil {
+		return GameReplayResult{}, err
+	}
+
+	varianceFactory, err := getVarianceFactory(defenition)
+	if err != nil {
+		return GameReplayResult{}, err
+	}
+
+	processedGameBoard := newEmptyGameBoard(defenition, varianceFactory)
+	result := GameReplayResult{
+		GameId:           defenition.Id,
+		PlayerIds:        defenition.PlayerIds,
+		InitialGameBoard: processedGameBoard.clone().GameBoard,
+		Steps:            make([]GameReplayStep, 0),
+	}
+
+	for _, event := range defenition.Events {
+		processedGameBoard, err = ProcessEvents(processedGameBoard, []GameEvent{event})
+		if err != nil {
+			return GameReplayResult{}, err
+		}
+
+		step := GameReplayStep{
+			Event:       event,
+			GameBoard:   processedGameBoard.clone().GameBoard,
+			Deflections: make([]Deflection, 0),
+		}
+		if _, ok := event.(FireDeflectorEvent); ok {
+			step.Deflections = processedGameBoard.LastDeflections
+		}
+		result.Steps = append(result.Steps, step)
+	}
+
+	return result, nil
+}
+
 func (useCase UseCase) GetOngoingGameId(playerId string) (string, error) {
 	dbGameBoard, err := useCase.Repo.GetOngoingPlayerGame(playerId)
 	if err != nil {
@@ -134,6 +514,9 @@ type AddPawnRequest struct {
 	X          int
 	Y          int
 	PlayerSide string
+	// Twin links the new portal to the portal of the player at this position
+	Twin               *Position
+	ExpectedEventCount *int
 }
 
 type AddPawnResult struct {
@@ -142,6 +525,7 @@ type AddPawnResult struct {
 	NewPawn                        Pawn
 	Deflections                    []Deflection
 	PostDeflectionPartialGameBoard PostDeflectionPartialGameBoard
+	Clocks                         map[string]int64
 	EventCount                     int
 	PreviousEventCount             int
 }
@@ -159,20 +543,30 @@ func (res AddPawnResult) ToMap() map[string]interface{} {
 		"postDeflectionPartialGameBoard": res.PostDeflectionPartialGameBoard,
 		"variants":                       res.Variants,
 		"scoreBoard":                     res.ScoreBoard,
+		"clocks":                         res.Clocks,
 		"eventCount":                     res.EventCount,
 		"previousEventCount":             res.PreviousEventCount,
 	}
 }
 
 func (useCase UseCase) AddPawn(gameId string, addPawnRequest AddPawnRequest) (AddPawnResult, error) {
-	processedGameBoard, err := getLockedProcessedGameBoard(useCase.Repo, gameId)
+	processedGameBoard, unlock, err := getLockedProcessedGameBoard(useCase.Repo, gameId)
+
+	if err != nil {
+		return AddPawnResult{}, err
+	}
+	defer unlock()
 
+	err = checkEventCount(processedGameBoard, addPawnRequest.ExpectedEventCount)
 	if err != nil {
 		return AddPawnResult{}, err
 	}
 	previousEventCount := len(processedGameBoard.GameBoard.defenition.Events)
 
 	pawnEvent := NewCreatePawnEvent(NewPosition(addPawnRequest.X, addPawnRequest.Y), addPawnRequest.PlayerSide)
+	if addPawnRequest.Twin != nil {
+		pawnEvent = NewCreateLinkedPawnEvent(NewPosition(addPawnRequest.X, addPawnRequest.Y), *addPawnRequest.Twin, addPawnRequest.PlayerSide)
+	}
 	var newEvents []GameEvent
 
 	newEvents = append(newEvents, pawnEvent)
@@ -180,31 +574,24 @@ func (useCase UseCase) AddPawn(gameId string, addPawnRequest AddPawnRequest) (Ad
 	processedGameBoard, err = ProcessEvents(processedGameBoard, newEvents)
 
 	if err != nil {
-		useCase.Repo.UnlockGame(gameId)
 		return AddPawnResult{}, err
 	}
 
 	newPawn, err := processedGameBoard.GameBoard.GetPawn(NewPosition(addPawnRequest.X, addPawnRequest.Y))
 
 	if err != nil {
-		useCase.Repo.UnlockGame(gameId)
 		return AddPawnResult{}, err
 	}
 
-	err = useCase.Repo.ReplaceGame(gameId, getInsertDefenition(processedGameBoard.GameBoard.defenition))
+	err = saveNewEvents(useCase.Repo, gameId, processedGameBoard, previousEventCount)
 	if err != nil {
-		useCase.Repo.UnlockGame(gameId)
 		return AddPawnResult{}, err
 	}
+	unlock()
 	eventCount := len(processedGameBoard.GameBoard.defenition.Events)
 
-	nextProcessedGameBoard, err := NewGameBoard(processedGameBoard.GameBoard.GetDefenition())
-	if err != nil {
-		return AddPawnResult{}, err
-	}
-
 	fireEvent := NewFireDeflectorEvent()
-	nextProcessedGameBoard, err = ProcessEvents(nextProcessedGameBoard, []GameEvent{fireEvent})
+	nextProcessedGameBoard, err := ProcessEvents(processedGameBoard.clone(), []GameEvent{fireEvent})
 	if err != nil {
 		return AddPawnResult{}, err
 	}
@@ -218,6 +605,7 @@ func (useCase UseCase) AddPawn(gameId string, addPawnRequest AddPawnRequest) (Ad
 			PreviousScoreBoard: processedGameBoard.GameBoard.ScoreBoard,
 			ScoreBoard:         nextProcessedGameBoard.GameBoard.ScoreBoard,
 		},
+		Clocks:             processedGameBoard.Clocks,
 		EventCount:         eventCount,
 		PreviousEventCount: previousEventCount,
 	}
@@ -228,6 +616,104 @@ func (useCase UseCase) AddPawn(gameId string, addPawnRequest AddPawnRequest) (Ad
 	return result, nil
 }
 
+type UndoPawnResult struct {
+	ScoreBoard                     map[string]int
+	Variants                       map[string][]string
+	AvailableUndos                 map[string]int
+	Position                       Position
+	Pawn                           Pawn
+	Deflections                    []Deflection
+	PostDeflectionPartialGameBoard PostDeflectionPartialGameBoard
+	Clocks                         map[string]int64
+	EventCount                     int
+	PreviousEventCount             int
+}
+
+func (res UndoPawnResult) ToMap() map[string]interface{} {
+
+	deflections := make([]map[string]interface{}, 0)
+	for i := 0; i < len(res.Deflections); i++ {
+		deflections = append(deflections, res.Deflections[i].toMap())
+	}
+
+	return map[string]interface{}{
+		"position":                       res.Position.toMap(),
+		"pawn":                           res.Pawn.toMap(),
+		"deflections":                    deflections,
+		"postDeflectionPartialGameBoard": res.PostDeflectionPartialGameBoard,
+		"variants":                       res.Variants,
+		"scoreBoard":                     res.ScoreBoard,
+		"availableUndos":                 res.AvailableUndos,
+		"clocks":                         res.Clocks,
+		"eventCount":                     res.EventCount,
+		"previousEventCount":             res.PreviousEventCount,
+	}
+}
+
+// UndoPawn reverts the last pawn the player placed in the current turn,
+// Pawn is the pawn that is now at its position, an empty pawn if the position was free
+func (useCase UseCase) UndoPawn(gameId string, playerSide string, expectedEventCount *int) (UndoPawnResult, error) {
+	processedGameBoard, unlock, err := getLockedProcessedGameBoard(useCase.Repo, gameId)
+
+	if err != nil {
+		return UndoPawnResult{}, err
+	}
+	defer unlock()
+
+	err = checkEventCount(processedGameBoard, expectedEventCount)
+	if err != nil {
+		return UndoPawnResult{}, err
+	}
+	previousEventCount := len(processedGameBoard.GameBoard.defenition.Events)
+	placements := processedGameBoard.UndoablePlacements
+
+	undoEvent := NewUndoPawnEvent(playerSide)
+	processedGameBoard, err = ProcessEvents(processedGameBoard, []GameEvent{undoEvent})
+	if err != nil {
+		return UndoPawnResult{}, err
+	}
+	undonePlacement := placements[len(placements)-1]
+
+	err = saveNewEvents(useCase.Repo, gameId, processedGameBoard, previousEventCount)
+	if err != nil {
+		return UndoPawnResult{}, err
+	}
+	unlock()
+	eventCount := len(processedGameBoard.GameBoard.defenition.Events)
+
+	fireEvent := NewFireDeflectorEvent()
+	nextProcessedGameBoard, err := ProcessEvents(processedGameBoard.clone(), []GameEvent{fireEvent})
+	if err != nil {
+		return UndoPawnResult{}, err
+	}
+
+	pawn := Pawn{}
+	if undonePlacement.ReplacedPawn != nil {
+		pawn = *undonePlacement.ReplacedPawn
+	}
+
+	result := UndoPawnResult{
+		ScoreBoard:     processedGameBoard.GameBoard.ScoreBoard,
+		Variants:       processedGameBoard.PawnVariants,
+		AvailableUndos: processedGameBoard.AvailableUndos,
+		Position:       undonePlacement.Position,
+		Pawn:           pawn,
+		Deflections:    nextProcessedGameBoard.LastDeflections,
+		PostDeflectionPartialGameBoard: PostDeflectionPartialGameBoard{
+			PreviousScoreBoard: processedGameBoard.GameBoard.ScoreBoard,
+			ScoreBoard:         nextProcessedGameBoard.GameBoard.ScoreBoard,
+		},
+		Clocks:             processedGameBoard.Clocks,
+		EventCount:         eventCount,
+		PreviousEventCount: previousEventCount,
+	}
+
+	broadcastIds := getBroadcastIds(processedGameBoard, playerSide)
+	network.SocketBroadcast(broadcastIds, "undo", result.ToMap())
+
+	return result, nil
+}
+
 type PostDeflectionPartialGameBoard struct {
 	PreviousScoreBoard map[string]int `json:"previousScoreBoard"`
 	ScoreBoard         map[string]int `json:"scoreBoard"`
@@ -240,11 +726,14 @@ type EndTurnResult struct {
 	AllDeflections                     [][]Deflection
 	AllPostDeflectionPartialGameBoards []PostDeflectionPartialGameBoard
 	Winner                             string
+	EndReason                          string
+	EliminatedPlayers                  map[string]bool
 	MatchPointPlayers                  map[string]bool
 	AvailableShuffles                  map[string]int
 	Deflections                        []Deflection
 	PostDeflectionPartialGameBoard     PostDeflectionPartialGameBoard
 	LastTurnEndTime                    int64
+	Clocks                             map[string]int64
 	EventCount                         int
 	PreviousEventCount                 int
 }
@@ -270,9 +759,12 @@ func (res EndTurnResult) ToMap() map[string]interface{} {
 		"playerTurn":                         res.PlayerTurn,
 		"allDeflections":                     allDeflections,
 		"winner":                             res.Winner,
+		"endReason":                          res.EndReason,
+		"eliminatedPlayers":                  res.EliminatedPlayers,
 		"matchPointPlayers":                  res.MatchPointPlayers,
 		"availableShuffles":                  res.AvailableShuffles,
 		"deflections":                        deflections,
+		"clocks":                             res.Clocks,
 		"eventCount":                         res.EventCount,
 		"previousEventCount":                 res.PreviousEventCount,
 		"lastTurnEndTime":                    res.LastTurnEndTime,
@@ -281,9 +773,15 @@ func (res EndTurnResult) ToMap() map[string]interface{} {
 	}
 }
 
-func (useCase UseCase) EndTurn(gameId string, playerSide string) (EndTurnResult, error) {
-	processedGameBoard, err := getLockedProcessedGameBoard(useCase.Repo, gameId)
+func (useCase UseCase) EndTurn(gameId string, playerSide string, expectedEventCount *int) (EndTurnResult, error) {
+	processedGameBoard, unlock, err := getLockedProcessedGameBoard(useCase.Repo, gameId)
+
+	if err != nil {
+		return EndTurnResult{}, err
+	}
+	defer unlock()
 
+	err = checkEventCount(processedGameBoard, expectedEventCount)
 	if err != nil {
 		return EndTurnResult{}, err
 	}
@@ -291,36 +789,44 @@ func (useCase UseCase) EndTurn(gameId string, playerSide string) (EndTurnResult,
 	if err != nil {
 		return EndTurnResult{}, err
 	}
+	unlock()
 	broadcastIds := getBroadcastIds(processedGameBoard, playerSide)
 	network.SocketBroadcast(broadcastIds, "turn", endResult.ToMap())
 
-	if processedGameBoard.Winner != "" {
-		notifyUserServiceOfGameEnd(useCase.Repo, processedGameBoard.GameBoard.defenition.PlayerIds)
+	if endResult.Winner != "" {
+		notifyUserServiceOfGameEnd(useCase.Repo, processedGameBoard.GameBoard.defenition)
 	}
 
 	return endResult, nil
 }
 
 func (useCase UseCase) ExpireTurn(gameId string, playerSide string, eventCount int) (EndTurnResult, error) {
-	processedGameBoard, err := getLockedProcessedGameBoard(useCase.Repo, gameId)
+	return useCase.expireTurn(gameId, eventCount, repositories.LockGame)
+}
+
+func (useCase UseCase) expireTurn(gameId string, eventCount int, lockGame lockGameFunc) (EndTurnResult, error) {
+	processedGameBoard, unlock, err := lockProcessedGameBoard(useCase.Repo, gameId, lockGame)
 
 	if err != nil {
 		return EndTurnResult{}, err
 	}
+	defer unlock()
 
 	if len(processedGameBoard.GameBoard.defenition.Events) != eventCount {
-		return EndTurnResult{}, errors.New("turn already ended")
+		return EndTurnResult{}, ErrTurnAlreadyEnded
 	}
 
 	endResult, err := endGameTurn(useCase.Repo, processedGameBoard, "system")
 	if err != nil {
 		return EndTurnResult{}, err
 	}
+	unlock()
 
-	network.SocketBroadcast(processedGameBoard.GameBoard.defenition.PlayerIds, "turn", endResult.ToMap())
+	broadcastIds := getBroadcastIds(processedGameBoard, "system")
+	network.SocketBroadcast(broadcastIds, "turn", endResult.ToMap())
 
 	if endResult.Winner != "" {
-		notifyUserServiceOfGameEnd(useCase.Repo, processedGameBoard.GameBoard.defenition.PlayerIds)
+		notifyUserServiceOfGameEnd(useCase.Repo, processedGameBoard.GameBoard.defenition)
 	}
 
 	return endResult, nil
@@ -343,13 +849,10 @@ func endGameTurn(repo repositories.Repository, processedGameBoard ProcessedGameB
 		var err error
 		processedGameBoard, err = ProcessEvents(processedGameBoard, []GameEvent{fireEvent})
 		if err != nil {
-			repo.UnlockGame(gameId)
 			return EndTurnResult{}, err
 		}
 
 		if len(processedGameBoard.LastDeflections) > 1 {
-			lastDirection := processedGameBoard.LastDeflections[len(processedGameBoard.LastDeflections)-1].ToDirection
-
 			partialGameBoards = append(partialGameBoards, PostDeflectionPartialGameBoard{
 				PreviousScoreBoard: scoreBoard,
 				ScoreBoard:         processedGameBoard.GameBoard.CopyScoreBoard(),
@@ -357,14 +860,21 @@ func endGameTurn(repo repositories.Repository, processedGameBoard ProcessedGameB
 			allDeflections = append(allDeflections, processedGameBoard.LastDeflections)
 			isDense = processedGameBoard.GameBoard.IsDense()
 
-			playerId, ok := GetPlayerFromDirection(processedGameBoard.GameBoard.GetDefenition(), lastDirection)
+			// a split deflector can exit from several edges, the first side in match point to be reached wins
+			winner := ""
+			for _, direction := range getExitDirections(processedGameBoard.LastDeflections) {
+				playerId, ok := GetPlayerFromDirection(processedGameBoard.GameBoard.GetDefenition(), direction)
+				if ok && isActiveSide(processedGameBoard, playerId) && processedGameBoard.PlayersInMatchPoint[playerId] {
+					winner = playerId
+					break
+				}
+			}
 
-			if ok && processedGameBoard.PlayersInMatchPoint[playerId] {
-				winEvent := NewWinEvent(playerId)
+			if winner != "" {
+				winEvent := NewWinEvent(winner, END_REASON_SCORE)
 				processedGameBoard, err = ProcessEvents(processedGameBoard, []GameEvent{winEvent})
 
 				if err != nil {
-					repo.UnlockGame(gameId)
 					return EndTurnResult{}, err
 				}
 				break
@@ -376,36 +886,44 @@ func endGameTurn(repo repositories.Repository, processedGameBoard ProcessedGameB
 	processedGameBoard, err := ProcessEvents(processedGameBoard, []GameEvent{endTurnEvent})
 
 	if err != nil {
-		repo.UnlockGame(gameId)
 		return EndTurnResult{}, err
 	}
 
+	if processedGameBoard.GameInProgress {
+		flagFallEvents := GetFlagFallEvents(processedGameBoard)
+		processedGameBoard, err = ProcessEvents(processedGameBoard, flagFallEvents)
+
+		if err != nil {
+			return EndTurnResult{}, err
+		}
+	}
+
+	if processedGameBoard.GameInProgress {
+		forfeitEvents := GetForfeitEvents(processedGameBoard)
+		processedGameBoard, err = ProcessEvents(processedGameBoard, forfeitEvents)
+
+		if err != nil {
+			return EndTurnResult{}, err
+		}
+	}
+
 	if processedGameBoard.GameInProgress {
 		matchPointEvents := GetMatchPointEvents(processedGameBoard)
 		processedGameBoard, err = ProcessEvents(processedGameBoard, matchPointEvents)
 
 		if err != nil {
-			repo.UnlockGame(gameId)
 			return EndTurnResult{}, err
 		}
 	}
 
-	insert := getInsertDefenition(processedGameBoard.GameBoard.defenition)
-	insert.Winner = processedGameBoard.Winner
-
-	err = repo.ReplaceGame(gameId, insert)
+	err = saveNewEvents(repo, gameId, processedGameBoard, previousEventCount)
 	if err != nil {
-		repo.UnlockGame(gameId)
 		return EndTurnResult{}, err
 	}
 	eventCount := len(processedGameBoard.GameBoard.defenition.Events)
 
-	nextProcessedGameBoard, err := NewGameBoard(processedGameBoard.GameBoard.GetDefenition())
-	if err != nil {
-		return EndTurnResult{}, err
-	}
 	fireEvent := NewFireDeflectorEvent()
-	nextProcessedGameBoard, err = ProcessEvents(nextProcessedGameBoard, []GameEvent{fireEvent})
+	nextProcessedGameBoard, err := ProcessEvents(processedGameBoard.clone(), []GameEvent{fireEvent})
 
 	if err != nil {
 		return EndTurnResult{}, err
@@ -416,15 +934,18 @@ func endGameTurn(repo repositories.Repository, processedGameBoard ProcessedGameB
 		Variants:                           processedGameBoard.PawnVariants,
 		PlayerTurn:                         GetPlayerTurn(processedGameBoard.GameBoard),
 		Winner:                             processedGameBoard.Winner,
+		EndReason:                          processedGameBoard.EndReason,
 		AllDeflections:                     allDeflections,
 		AllPostDeflectionPartialGameBoards: partialGameBoards,
 		AvailableShuffles:                  processedGameBoard.AvailableShuffles,
+		EliminatedPlayers:                  processedGameBoard.EliminatedPlayers,
 		MatchPointPlayers:                  processedGameBoard.PlayersInMatchPoint,
 		Deflections:                        nextProcessedGameBoard.LastDeflections,
 		PostDeflectionPartialGameBoard: PostDeflectionPartialGameBoard{
 			PreviousScoreBoard: processedGameBoard.GameBoard.ScoreBoard,
 			ScoreBoard:         nextProcessedGameBoard.GameBoard.ScoreBoard,
 		},
+		Clocks:             processedGameBoard.Clocks,
 		EventCount:         eventCount,
 		PreviousEventCount: previousEventCount,
 		LastTurnEndTime:    processedGameBoard.LastTurnEndTime,
@@ -432,9 +953,152 @@ func endGameTurn(repo repositories.Repository, processedGameBoard ProcessedGameB
 	return result, nil
 }
 
+type ResignResult struct {
+	Winner             string
+	EndReason          string
+	PlayerTurn         string
+	EliminatedPlayers  map[string]bool
+	Clocks             map[string]int64
+	EventCount         int
+	PreviousEventCount int
+}
+
+func (res ResignResult) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"winner":             res.Winner,
+		"endReason":          res.EndReason,
+		"playerTurn":         res.PlayerTurn,
+		"eliminatedPlayers":  res.EliminatedPlayers,
+		"clocks":             res.Clocks,
+		"eventCount":         res.EventCount,
+		"previousEventCount": res.PreviousEventCount,
+	}
+}
+
+func (useCase UseCase) Resign(gameId string, playerSide string, expectedEventCount *int) (ResignResult, error) {
+	processedGameBoard, unlock, err := getLockedProcessedGameBoard(useCase.Repo, gameId)
+
+	if err != nil {
+		return ResignResult{}, err
+	}
+	defer unlock()
+
+	err = checkEventCount(processedGameBoard, expectedEventCount)
+	if err != nil {
+		return ResignResult{}, err
+	}
+	previousEventCount := len(processedGameBoard.GameBoard.defenition.Events)
+
+	var resignEvent GameEvent = NewResignEvent(playerSide)
+	if len(getActiveSides(processedGameBoard)) > 2 {
+		resignEvent = NewEliminatePlayerEvent(playerSide, END_REASON_RESIGN)
+	}
+	processedGameBoard, err = ProcessEvents(processedGameBoard, []GameEvent{resignEvent})
+	if err != nil {
+		return ResignResult{}, err
+	}
+
+	err = saveNewEvents(useCase.Repo, gameId, processedGameBoard, previousEventCount)
+	if err != nil {
+		return ResignResult{}, err
+	}
+	unlock()
+
+	result := ResignResult{
+		Winner:             processedGameBoard.Winner,
+		EndReason:          processedGameBoard.EndReason,
+		PlayerTurn:         GetPlayerTurn(processedGameBoard.GameBoard),
+		EliminatedPlayers:  processedGameBoard.EliminatedPlayers,
+		Clocks:             processedGameBoard.Clocks,
+		EventCount:         len(processedGameBoard.GameBoard.defenition.Events),
+		PreviousEventCount: previousEventCount,
+	}
+
+	broadcastIds := getBroadcastIds(processedGameBoard, playerSide)
+	network.SocketBroadcast(broadcastIds, "resign", result.ToMap())
+
+	if !processedGameBoard.GameInProgress {
+		notifyUserServiceOfGameEnd(useCase.Repo, processedGameBoard.GameBoard.defenition)
+	}
+
+	return result, nil
+}
+
+type DrawResult struct {
+	DrawOfferedBy      string
+	IsDraw             bool
+	Clocks             map[string]int64
+	EventCount         int
+	PreviousEventCount int
+}
+
+func (res DrawResult) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"drawOfferedBy":      res.DrawOfferedBy,
+		"isDraw":             res.IsDraw,
+		"clocks":             res.Clocks,
+		"eventCount":         res.EventCount,
+		"previousEventCount": res.PreviousEventCount,
+	}
+}
+
+func (useCase UseCase) OfferDraw(gameId string, playerSide string, expectedEventCount *int) (DrawResult, error) {
+	return processDrawEvent(useCase.Repo, gameId, playerSide, NewOfferDrawEvent(playerSide), expectedEventCount)
+}
+
+func (useCase UseCase) AcceptDraw(gameId string, playerSide string, expectedEventCount *int) (DrawResult, error) {
+	return processDrawEvent(useCase.Repo, gameId, playerSide, NewAcceptDrawEvent(playerSide), expectedEventCount)
+}
+
+func processDrawEvent(repo repositories.Repository, gameId string, playerSide string, drawEvent GameEvent, expectedEventCount *int) (DrawResult, error) {
+	processedGameBoard, unlock, err := getLockedProcessedGameBoard(repo, gameId)
+
+	if err != nil {
+		return DrawResult{}, err
+	}
+	defer unlock()
+
+	err = checkEventCount(processedGameBoard, expectedEventCount)
+	if err != nil {
+		return DrawResult{}, err
+	}
+	previousEventCount := len(processedGameBoard.GameBoard.defenition.Events)
+
+	processedGameBoard, err = ProcessEvents(processedGameBoard, []GameEvent{drawEvent})
+	if err != nil {
+		return DrawResult{}, err
+	}
+
+	err = saveNewEvents(repo, gameId, processedGameBoard, previousEventCount)
+	if err != nil {
+		return DrawResult{}, err
+	}
+	unlock()
+
+	result := DrawResult{
+		DrawOfferedBy:      processedGameBoard.DrawOfferedBy,
+		IsDraw:             processedGameBoard.IsDraw,
+		Clocks:             processedGameBoard.Clocks,
+		EventCount:         len(processedGameBoard.GameBoard.defenition.Events),
+		PreviousEventCount: previousEventCount,
+	}
+
+	broadcastIds := getBroadcastIds(processedGameBoard, playerSide)
+	if result.IsDraw {
+		network.SocketBroadcast(broadcastIds, "draw", result.ToMap())
+		notifyUserServiceOfGameEnd(repo, processedGameBoard.GameBoard.defenition)
+	} else {
+		network.SocketBroadcast(broadcastIds, "draw_offer", result.ToMap())
+	}
+
+	return result, nil
+}
+
 type PlayerStats struct {
 	Games       int
 	Wins        int
+	Draws       int
+	Losses      int
 	HasWonToday bool
 	WinStreak   int
 	NextDay     int64
@@ -444,6 +1108,8 @@ func (stats PlayerStats) ToMap() map[string]interface{} {
 	return map[string]interface{}{
 		"games":       stats.Games,
 		"wins":        stats.Wins,
+		"draws":       stats.Draws,
+		"losses":      stats.Losses,
 		"hasWonToday": stats.HasWonToday,
 		"winStreak":   stats.WinStreak,
 		"nextDay":     stats.NextDay,
@@ -464,20 +1130,29 @@ func (useCase UseCase) GetPlayerStats(playerId string) (PlayerStats, error) {
 	return PlayerStats{
 		Games:       winStats[0].Games,
 		Wins:        winStats[0].Wins,
+		Draws:       winStats[0].Draws,
+		Losses:      winStats[0].Losses,
 		HasWonToday: winStreak.HasWonToday,
 		WinStreak:   winStreak.WinStreak,
 		NextDay:     winStreak.NextDay,
 	}, nil
 }
 
-func notifyUserServiceOfGameEnd(repo repositories.Repository, playerIds []string) {
-	repoStatUpdates, err := repo.GetPlayersGameStats(playerIds)
+func notifyUserServiceOfGameEnd(repo repositories.Repository, defenition GameBoardDefenition) {
+	// the stats of the players do not change when a fork ends
+	if defenition.ForkedFrom != "" {
+		return
+	}
+
+	repoStatUpdates, err := repo.GetPlayersGameStats(defenition.PlayerIds)
 	statUpdates := []network.GameEndUserUpdate{}
 	for i := 0; i < len(repoStatUpdates); i++ {
 		statUpdates = append(statUpdates, network.GameEndUserUpdate{
 			PlayerId: repoStatUpdates[i].PlayerId,
 			Games:    repoStatUpdates[i].Games,
 			Wins:     repoStatUpdates[i].Wins,
+			Draws:    repoStatUpdates[i].Draws,
+			Losses:   repoStatUpdates[i].Losses,
 		})
 	}
 
@@ -489,6 +1164,7 @@ func notifyUserServiceOfGameEnd(repo repositories.Repository, playerIds []string
 type ShuffleResult struct {
 	Variants           map[string][]string
 	AvailableShuffles  map[string]int
+	Clocks             map[string]int64
 	EventCount         int
 	PreviousEventCount int
 }
@@ -497,14 +1173,21 @@ func (res ShuffleResult) ToMap() map[string]interface{} {
 	return map[string]interface{}{
 		"variants":           res.Variants,
 		"availableShuffles":  res.AvailableShuffles,
+		"clocks":             res.Clocks,
 		"eventCount":         res.EventCount,
 		"previousEventCount": res.PreviousEventCount,
 	}
 }
 
-func (useCase UseCase) Shuffle(gameId string, playerSide string) (ShuffleResult, error) {
-	processedGameBoard, err := getLockedProcessedGameBoard(useCase.Repo, gameId)
+func (useCase UseCase) Shuffle(gameId string, playerSide string, expectedEventCount *int) (ShuffleResult, error) {
+	processedGameBoard, unlock, err := getLockedProcessedGameBoard(useCase.Repo, gameId)
 
+	if err != nil {
+		return ShuffleResult{}, err
+	}
+	defer unlock()
+
+	err = checkEventCount(processedGameBoard, expectedEventCount)
 	if err != nil {
 		return ShuffleResult{}, err
 	}
@@ -513,20 +1196,20 @@ func (useCase UseCase) Shuffle(gameId string, playerSide string) (ShuffleResult,
 	skipEvent := NewSkipPawnEvent(playerSide)
 	processedGameBoard, err = ProcessEvents(processedGameBoard, []GameEvent{skipEvent})
 	if err != nil {
-		useCase.Repo.UnlockGame(gameId)
 		return ShuffleResult{}, err
 	}
 
-	err = useCase.Repo.ReplaceGame(gameId, getInsertDefenition(processedGameBoard.GameBoard.defenition))
+	err = saveNewEvents(useCase.Repo, gameId, processedGameBoard, previousEventCount)
 	if err != nil {
-		useCase.Repo.UnlockGame(gameId)
 		return ShuffleResult{}, err
 	}
+	unlock()
 	eventCount := len(processedGameBoard.GameBoard.defenition.Events)
 
 	result := ShuffleResult{
 		Variants:           processedGameBoard.PawnVariants,
 		AvailableShuffles:  processedGameBoard.AvailableShuffles,
+		Clocks:             processedGameBoard.Clocks,
 		EventCount:         eventCount,
 		PreviousEventCount: previousEventCount,
 	}
@@ -546,6 +1229,7 @@ type PeekRequest struct {
 type PeekResult struct {
 	NewPawn                        Pawn
 	Deflections                    []Deflection
+	Clocks                         map[string]int64
 	EventCount                     int
 	PreviousEventCount             int
 	PostDeflectionPartialGameBoard PostDeflectionPartialGameBoard
@@ -561,6 +1245,7 @@ func (res PeekResult) ToMap() map[string]interface{} {
 	return map[string]interface{}{
 		"newPawn":                        res.NewPawn.toMap(),
 		"deflections":                    deflections,
+		"clocks":                         res.Clocks,
 		"eventCount":                     res.EventCount,
 		"previousEventCount":             res.PreviousEventCount,
 		"postDeflectionPartialGameBoard": res.PostDeflectionPartialGameBoard,
@@ -604,6 +1289,7 @@ func (useCase UseCase) Peek(gameId string, peekRequest PeekRequest) (PeekResult,
 			PreviousScoreBoard: scoreBoard,
 			ScoreBoard:         processedGameBoard.GameBoard.ScoreBoard,
 		},
+		Clocks:             processedGameBoard.Clocks,
 		EventCount:         previousEventCount,
 		PreviousEventCount: previousEventCount,
 	}
@@ -614,8 +1300,13 @@ func (useCase UseCase) Peek(gameId string, peekRequest PeekRequest) (PeekResult,
 	return result, nil
 }
 
+// getBroadcastIds gives the players to notify of a move, nobody is notified of the moves made in a fork
+// since its players may be in another game at the same time
 func getBroadcastIds(processedGameBoard ProcessedGameBoard, currentPlayer string) []string {
 	broadcastIds := make([]string, 0)
+	if processedGameBoard.GameBoard.GetDefenition().ForkedFrom != "" {
+		return broadcastIds
+	}
 	for i := 0; i < len(processedGameBoard.GameBoard.GetDefenition().PlayerIds); i++ {
 		id := processedGameBoard.GameBoard.GetDefenition().PlayerIds[i]
 		if id != currentPlayer {
@@ -0,0 +1,619 @@
+package gamemechanics
+
+import (
+	"errors"
+	"projectdeflector/game/repositories"
+	"reflect"
+	"testing"
+)
+
+func newTestUseCase(t *testing.T) UseCase {
+	repo, cleanup, err := repositories.NewMemoryRepositoryFactory().GetRepository()
+	if err != nil {
+		t.Fatalf("Failed to get repository: %v", err)
+	}
+	t.Cleanup(cleanup)
+	return UseCase{
+		Repo: repo,
+	}
+}
+
+// TEST_SEED makes the variants and the deflections of the test games the same on every run
+const TEST_SEED = "0123456789abcdef0123456789abcdef"
+
+// newTestGame stores a game with the standard rules and the test seed
+func newTestGame(t *testing.T, playerIds ...string) (UseCase, string) {
+	useCase := newTestUseCase(t)
+	defenition := NewGameBoardDefinition("", playerIds)
+	defenition.Seed = TEST_SEED
+	gameId, err := useCase.Repo.InsertGame(getInsertDefenition(defenition))
+	if err != nil {
+		t.Fatalf("Failed to insert game: %v", err)
+	}
+	return useCase, gameId
+}
+
+func TestExpectedEventCount(t *testing.T) {
+	useCase, gameId := newTestGame(t, "red", "blue")
+
+	staleEventCount := 3
+	_, err := useCase.AddPawn(gameId, AddPawnRequest{X: 0, Y: 0, PlayerSide: "red", ExpectedEventCount: &staleEventCount})
+	var conflict EventCountConflictError
+	if !errors.As(err, &conflict) || conflict.EventCount != 0 {
+		t.Fatalf("Expected an event count conflict, got %v", err)
+	}
+
+	eventCount := 0
+	result, err := useCase.AddPawn(gameId, AddPawnRequest{X: 0, Y: 0, PlayerSide: "red", ExpectedEventCount: &eventCount})
+	if err != nil {
+		t.Fatalf("Failed to add pawn: %v", err)
+	}
+	if result.EventCount != 1 || result.PreviousEventCount != 0 {
+		t.Errorf("Wrong event counts %d %d", result.PreviousEventCount, result.EventCount)
+	}
+
+	// a double tap sends the same request twice
+	_, err = useCase.AddPawn(gameId, AddPawnRequest{X: 1, Y: 1, PlayerSide: "red", ExpectedEventCount: &eventCount})
+	if !errors.As(err, &conflict) || conflict.EventCount != 1 {
+		t.Errorf("Expected an event count conflict, got %v", err)
+	}
+
+	_, err = useCase.Shuffle(gameId, "red", &eventCount)
+	if !errors.As(err, &conflict) {
+		t.Errorf("Expected an event count conflict, got %v", err)
+	}
+
+	_, err = useCase.EndTurn(gameId, "red", &eventCount)
+	if !errors.As(err, &conflict) {
+		t.Errorf("Expected an event count conflict, got %v", err)
+	}
+
+	_, err = useCase.EndTurn(gameId, "red", &result.EventCount)
+	if err != nil {
+		t.Errorf("Failed to end turn: %v", err)
+	}
+
+	_, err = useCase.Shuffle(gameId, "blue", nil)
+	if err != nil {
+		t.Errorf("Failed to shuffle without an expected event count: %v", err)
+	}
+}
+
+// playTestTurns places a pawn on the first empty position then ends the turn, until the game ends or the turns run out
+func playTestTurns(t *testing.T, useCase UseCase, gameId string, turns int) {
+	for i := 0; i < turns; i++ {
+		game, err := useCase.GetGame(gameId)
+		if err != nil {
+			t.Fatalf("Failed to get game: %v", err)
+		}
+		if !game.ProcessedGameBoard.GameInProgress {
+			return
+		}
+		gameBoard := game.ProcessedGameBoard.GameBoard
+		playerId := GetPlayerTurn(gameBoard)
+
+		if position, ok := getEmptyPosition(gameBoard); ok && gameBoard.ScoreBoard[playerId] > 0 {
+			_, err = useCase.AddPawn(gameId, AddPawnRequest{X: position.X, Y: position.Y, PlayerSide: playerId})
+			if err != nil {
+				t.Fatalf("Failed to add pawn: %v", err)
+			}
+		}
+
+		_, err = useCase.EndTurn(gameId, playerId, nil)
+		if err != nil {
+			t.Fatalf("Failed to end turn: %v", err)
+		}
+	}
+}
+
+func getEmptyPosition(gameBoard GameBoard) (Position, bool) {
+	for y := 0; y < len(gameBoard.Pawns); y++ {
+		for x := 0; x < len(gameBoard.Pawns[y]); x++ {
+			if gameBoard.Pawns[y][x] == nil {
+				return position(x, y), true
+			}
+		}
+	}
+	return Position{}, false
+}
+
+func TestGetGameReplay(t *testing.T) {
+	useCase, gameId := newTestGame(t, "red", "blue")
+
+	playTestTurns(t, useCase, gameId, 6)
+
+	replay, err := useCase.GetGameReplay(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get replay: %v", err)
+	}
+
+	game, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	if len(replay.Steps) != game.EventCount {
+		t.Fatalf("Expected %d steps, got %d", game.EventCount, len(replay.Steps))
+	}
+	if replay.InitialGameBoard.getPawnCount() != 0 {
+		t.Errorf("The initial game board should be empty")
+	}
+
+	for i, step := range replay.Steps {
+		_, isFire := step.Event.(FireDeflectorEvent)
+		if isFire != (len(step.Deflections) > 0) {
+			t.Errorf("Step %d should only have deflections if it fired the deflector", i)
+		}
+	}
+
+	lastStep := replay.Steps[len(replay.Steps)-1].toMap()
+	if !reflect.DeepEqual(lastStep["gameBoard"], game.ProcessedGameBoard.GameBoard.toMap()) {
+		t.Errorf("The last step of the replay is not the current game board")
+	}
+
+	// every step keeps its own board and score board
+	firstStep := replay.Steps[0].toMap()
+	if reflect.DeepEqual(firstStep["gameBoard"], lastStep["gameBoard"]) {
+		t.Errorf("The steps share the same game board")
+	}
+}
+
+func TestGetGameAt(t *testing.T) {
+	useCase, gameId := newTestGame(t, "red", "blue")
+
+	playTestTurns(t, useCase, gameId, 12)
+
+	replay, err := useCase.GetGameReplay(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get replay: %v", err)
+	}
+
+	for i, step := range replay.Steps {
+		game, err := useCase.GetGameAt(gameId, i+1)
+		if err != nil {
+			t.Fatalf("Failed to get game at %d: %v", i+1, err)
+		}
+		if game.EventCount != i+1 {
+			t.Errorf("Expected %d events, got %d", i+1, game.EventCount)
+		}
+		if !reflect.DeepEqual(game.ProcessedGameBoard.GameBoard.toMap(), step.GameBoard.toMap()) {
+			t.Errorf("Game at %d differs from the replay", i+1)
+		}
+	}
+
+	game, err := useCase.GetGameAt(gameId, 0)
+	if err != nil || game.EventCount != 0 || game.ProcessedGameBoard.GameBoard.getPawnCount() != 0 {
+		t.Errorf("Expected the initial game board, got %v", err)
+	}
+
+	current, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	game, err = useCase.GetGameAt(gameId, current.EventCount)
+	if err != nil || !reflect.DeepEqual(game.ToMap(), current.ToMap()) {
+		t.Errorf("The game at the last event index differs from the current game %v", err)
+	}
+
+	for _, eventIndex := range []int{-1, current.EventCount + 1} {
+		_, err = useCase.GetGameAt(gameId, eventIndex)
+		var outOfRange EventIndexOutOfRangeError
+		if !errors.As(err, &outOfRange) || outOfRange.EventCount != current.EventCount {
+			t.Errorf("Expected an out of range error for %d, got %v", eventIndex, err)
+		}
+	}
+}
+
+func TestForkGame(t *testing.T) {
+	useCase, gameId := newTestGame(t, "red", "blue")
+
+	playTestTurns(t, useCase, gameId, 12)
+
+	current, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	for _, eventIndex := range []int{0, 5, current.EventCount} {
+		forkId, err := useCase.ForkGame(gameId, eventIndex)
+		if err != nil {
+			t.Fatalf("Failed to fork at %d: %v", eventIndex, err)
+		}
+
+		original, err := useCase.GetGameAt(gameId, eventIndex)
+		if err != nil {
+			t.Fatalf("Failed to get game at %d: %v", eventIndex, err)
+		}
+		fork, err := useCase.GetGame(forkId)
+		if err != nil {
+			t.Fatalf("Failed to get fork: %v", err)
+		}
+
+		if fork.ProcessedGameBoard.GameBoard.defenition.ForkedFrom != gameId || fork.EventCount != eventIndex {
+			t.Errorf("Fork at %d is not flagged", eventIndex)
+		}
+		if !reflect.DeepEqual(fork.ProcessedGameBoard.PawnVariants, original.ProcessedGameBoard.PawnVariants) ||
+			!reflect.DeepEqual(fork.NextProcessedGameBoard.LastDeflections, original.NextProcessedGameBoard.LastDeflections) {
+			t.Errorf("Fork at %d does not have the variance of the original game", eventIndex)
+		}
+	}
+
+	_, err = useCase.ForkGame(gameId, current.EventCount+1)
+	var outOfRange EventIndexOutOfRangeError
+	if !errors.As(err, &outOfRange) {
+		t.Errorf("Expected an out of range error, got %v", err)
+	}
+
+	// the fork is played with the normal flow without touching the original game
+	forkId, err := useCase.ForkGame(gameId, 0)
+	if err != nil {
+		t.Fatalf("Failed to fork: %v", err)
+	}
+	statsBefore := map[string]PlayerStats{}
+	for _, playerId := range []string{"red", "blue"} {
+		statsBefore[playerId], err = useCase.GetPlayerStats(playerId)
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+	}
+	playTestTurns(t, useCase, forkId, 2)
+	if ongoingId, err := useCase.GetOngoingGameId("red"); err == nil && ongoingId == forkId {
+		t.Errorf("The fork is the ongoing game of red")
+	}
+
+	// the seeded fork is won by scoring, which does not end the original game either
+	playTestTurns(t, useCase, forkId, 100)
+
+	fork, err := useCase.GetGame(forkId)
+	if err != nil {
+		t.Fatalf("Failed to get fork: %v", err)
+	}
+	after, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if after.EventCount != current.EventCount || !reflect.DeepEqual(after.ToMap(), current.ToMap()) {
+		t.Errorf("Playing the fork changed the original game")
+	}
+	if fork.ProcessedGameBoard.Winner == "" || fork.ProcessedGameBoard.EndReason != END_REASON_SCORE {
+		t.Fatalf("Expected the fork to be won by scoring, got the winner %q by %s", fork.ProcessedGameBoard.Winner, fork.ProcessedGameBoard.EndReason)
+	}
+	if fork.ProcessedGameBoard.GameBoard.defenition.ForkedFrom != gameId {
+		t.Errorf("Expected the fork to still be flagged")
+	}
+
+	for playerId, before := range statsBefore {
+		stats, err := useCase.GetPlayerStats(playerId)
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		if stats != before {
+			t.Errorf("The fork counted for the stats of %s %+v", playerId, stats)
+		}
+	}
+}
+
+func TestUndoPawn(t *testing.T) {
+	useCase, gameId := newTestGame(t, "red", "blue")
+
+	before, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+
+	if _, err = useCase.UndoPawn(gameId, "red", nil); err == nil {
+		t.Errorf("Undid a pawn that was never placed")
+	}
+
+	_, err = useCase.AddPawn(gameId, AddPawnRequest{X: 1, Y: 1, PlayerSide: "red"})
+	if err != nil {
+		t.Fatalf("Failed to add pawn: %v", err)
+	}
+
+	if _, err = useCase.UndoPawn(gameId, "blue", nil); err == nil {
+		t.Errorf("Undid the pawn of another player")
+	}
+
+	result, err := useCase.UndoPawn(gameId, "red", nil)
+	if err != nil {
+		t.Fatalf("Failed to undo pawn: %v", err)
+	}
+	if result.Position != position(1, 1) || result.Pawn != (Pawn{}) || result.AvailableUndos["red"] != 0 {
+		t.Errorf("Wrong undo result %+v", result)
+	}
+
+	after, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if after.EventCount != 2 {
+		t.Errorf("Expected the pawn and the undo events, got %d events", after.EventCount)
+	}
+	if !reflect.DeepEqual(after.ProcessedGameBoard.GameBoard.toMap(), before.ProcessedGameBoard.GameBoard.toMap()) ||
+		!reflect.DeepEqual(after.ProcessedGameBoard.PawnVariants, before.ProcessedGameBoard.PawnVariants) {
+		t.Errorf("The undo did not revert the pawn, its score point and its variant")
+	}
+
+	_, err = useCase.AddPawn(gameId, AddPawnRequest{X: 1, Y: 1, PlayerSide: "red"})
+	if err != nil {
+		t.Fatalf("Failed to add pawn: %v", err)
+	}
+	if _, err = useCase.UndoPawn(gameId, "red", nil); err == nil {
+		t.Errorf("Undid more pawns than the turn allows")
+	}
+
+	// the next player gets the allowance back, and an undo gives back the pawn that was replaced
+	_, err = useCase.EndTurn(gameId, "red", nil)
+	if err != nil {
+		t.Fatalf("Failed to end turn: %v", err)
+	}
+	game, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	// a pawn only loses one durability to the deflector of the turn, so the pawn of red is still there
+	replacedPawn, err := game.ProcessedGameBoard.GameBoard.GetPawn(position(1, 1))
+	if err != nil || replacedPawn.PlayerOwner != "red" {
+		t.Fatalf("Expected the pawn of red to be on the board: %v", err)
+	}
+
+	_, err = useCase.AddPawn(gameId, AddPawnRequest{X: 1, Y: 1, PlayerSide: "blue"})
+	if err != nil {
+		t.Fatalf("Failed to add pawn: %v", err)
+	}
+	result, err = useCase.UndoPawn(gameId, "blue", nil)
+	if err != nil {
+		t.Fatalf("Failed to undo pawn: %v", err)
+	}
+	if result.Pawn != *replacedPawn {
+		t.Errorf("Expected %+v to be put back, got %+v", *replacedPawn, result.Pawn)
+	}
+
+	after, err = useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if !reflect.DeepEqual(after.ProcessedGameBoard.GameBoard.toMap(), game.ProcessedGameBoard.GameBoard.toMap()) ||
+		!reflect.DeepEqual(after.ProcessedGameBoard.PawnVariants, game.ProcessedGameBoard.PawnVariants) {
+		t.Errorf("The undo did not revert the game")
+	}
+}
+
+func TestResign(t *testing.T) {
+	useCase, gameId := newTestGame(t, "red", "blue")
+
+	if _, err := useCase.Resign(gameId, "green", nil); err == nil {
+		t.Errorf("A player that is not in the game resigned")
+	}
+
+	// blue resigns during the turn of red
+	result, err := useCase.Resign(gameId, "blue", nil)
+	if err != nil {
+		t.Fatalf("Failed to resign: %v", err)
+	}
+	if result.Winner != "red" || result.EventCount != 1 {
+		t.Errorf("Wrong resign result %+v", result)
+	}
+
+	if _, err = useCase.Resign(gameId, "red", nil); err == nil {
+		t.Errorf("Resigned from a game that is over")
+	}
+
+	if _, err = useCase.GetOngoingGameId("blue"); err == nil {
+		t.Errorf("The resigned game is still ongoing")
+	}
+
+	stats, err := useCase.GetPlayerStats("red")
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Games != 1 || stats.Wins != 1 {
+		t.Errorf("The resigned game was not counted %+v", stats)
+	}
+}
+
+func TestDrawByAgreement(t *testing.T) {
+	useCase, gameId := newTestGame(t, "red", "blue")
+
+	if _, err := useCase.AcceptDraw(gameId, "blue", nil); err == nil {
+		t.Errorf("Accepted a draw that was never offered")
+	}
+
+	result, err := useCase.OfferDraw(gameId, "red", nil)
+	if err != nil {
+		t.Fatalf("Failed to offer a draw: %v", err)
+	}
+	if result.DrawOfferedBy != "red" || result.IsDraw {
+		t.Errorf("Wrong draw offer %+v", result)
+	}
+	if _, err = useCase.AcceptDraw(gameId, "red", nil); err == nil {
+		t.Errorf("A player accepted their own draw offer")
+	}
+
+	// the offer expires when the turn of the player who made it comes back
+	playTestTurns(t, useCase, gameId, 2)
+	if _, err = useCase.AcceptDraw(gameId, "blue", nil); err == nil {
+		t.Errorf("Accepted an expired draw offer")
+	}
+
+	_, err = useCase.OfferDraw(gameId, "blue", nil)
+	if err != nil {
+		t.Fatalf("Failed to offer a draw: %v", err)
+	}
+	result, err = useCase.AcceptDraw(gameId, "red", nil)
+	if err != nil {
+		t.Fatalf("Failed to accept the draw: %v", err)
+	}
+	if !result.IsDraw || result.DrawOfferedBy != "" {
+		t.Errorf("Wrong draw result %+v", result)
+	}
+
+	game, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	if game.ProcessedGameBoard.GameInProgress || game.ProcessedGameBoard.Winner != "" {
+		t.Errorf("The draw did not end the game without a winner")
+	}
+
+	if _, err = useCase.GetOngoingGameId("red"); err == nil {
+		t.Errorf("The drawn game is still ongoing")
+	}
+
+	for _, playerId := range []string{"red", "blue"} {
+		stats, err := useCase.GetPlayerStats(playerId)
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		if stats.Games != 1 || stats.Draws != 1 || stats.Wins != 0 || stats.Losses != 0 {
+			t.Errorf("Wrong stats for %s %+v", playerId, stats)
+		}
+	}
+}
+
+func TestPlayerCount(t *testing.T) {
+	useCase := newTestUseCase(t)
+
+	invalidPlayerIds := [][]string{
+		{"red"},
+		{"red", "blue", "green", "yellow", "purple"},
+		{"red", "blue", "red"},
+	}
+	for _, playerIds := range invalidPlayerIds {
+		if _, err := useCase.CreateNewGame(playerIds, getStandardRules()); err == nil {
+			t.Errorf("Created a game for %v", playerIds)
+		}
+	}
+}
+
+func TestEliminationInThreePlayerGame(t *testing.T) {
+	useCase, gameId := newTestGame(t, "red", "blue", "green")
+
+	if _, err := useCase.OfferDraw(gameId, "red", nil); err == nil {
+		t.Errorf("Offered a draw to more than one opponent")
+	}
+
+	// red resigns during their turn, the turn goes to the next player
+	result, err := useCase.Resign(gameId, "red", nil)
+	if err != nil {
+		t.Fatalf("Failed to resign: %v", err)
+	}
+	if result.Winner != "" || !result.EliminatedPlayers["red"] || result.PlayerTurn != "blue" {
+		t.Errorf("Wrong resign result %+v", result)
+	}
+	if _, err = useCase.GetOngoingGameId("red"); err == nil {
+		t.Errorf("The game is still ongoing for the eliminated player")
+	}
+	if _, err = useCase.GetOngoingGameId("blue"); err != nil {
+		t.Errorf("The game is over for the players that are left: %v", err)
+	}
+
+	// the turns skip the eliminated player
+	endTurnResult, err := useCase.EndTurn(gameId, "blue", nil)
+	if err != nil {
+		t.Fatalf("Failed to end turn: %v", err)
+	}
+	if endTurnResult.PlayerTurn != "green" {
+		t.Errorf("Expected the turn of green, got %s", endTurnResult.PlayerTurn)
+	}
+	endTurnResult, err = useCase.EndTurn(gameId, "green", nil)
+	if err != nil {
+		t.Fatalf("Failed to end turn: %v", err)
+	}
+	if endTurnResult.PlayerTurn != "blue" {
+		t.Errorf("Expected the turn of blue, got %s", endTurnResult.PlayerTurn)
+	}
+
+	// with two players left the game ends for the last one
+	result, err = useCase.Resign(gameId, "green", nil)
+	if err != nil {
+		t.Fatalf("Failed to resign: %v", err)
+	}
+	if result.Winner != "blue" || result.EndReason != END_REASON_RESIGN {
+		t.Errorf("Expected blue to win, got %+v", result)
+	}
+
+	stats, err := useCase.GetPlayerStats("red")
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Games != 1 || stats.Wins != 0 {
+		t.Errorf("The eliminated player did not lose %+v", stats)
+	}
+}
+
+func TestTeamGame(t *testing.T) {
+	useCase := newTestUseCase(t)
+
+	invalidTeams := [][][]string{
+		{{"red", "blue"}},
+		{{"red", "blue"}, {"green", "yellow"}, {"purple", "orange"}},
+		{{"red", "blue", "purple"}, {"green", "yellow"}},
+		{{"red", "blue"}, {"green", "red"}},
+	}
+	for _, teams := range invalidTeams {
+		if _, err := useCase.CreateNewTeamGame(teams, getStandardRules()); err == nil {
+			t.Errorf("Created a team game for %v", teams)
+		}
+	}
+
+	gameId, err := useCase.CreateNewTeamGame([][]string{{"red", "blue"}, {"green", "yellow"}}, getStandardRules())
+	if err != nil {
+		t.Fatalf("Failed to create game: %v", err)
+	}
+
+	game, err := useCase.GetGame(gameId)
+	if err != nil {
+		t.Fatalf("Failed to get game: %v", err)
+	}
+	scoreBoard := game.ProcessedGameBoard.GameBoard.ScoreBoard
+	if len(scoreBoard) != 2 || scoreBoard["team:1"] != 1 || scoreBoard["team:2"] != 0 {
+		t.Errorf("Expected a score per team, got %v", scoreBoard)
+	}
+
+	// the teams play alternately
+	for _, playerId := range []string{"red", "green", "blue", "yellow"} {
+		if GetPlayerTurn(game.ProcessedGameBoard.GameBoard) != playerId {
+			t.Fatalf("Expected the turn of %s, got %s", playerId, GetPlayerTurn(game.ProcessedGameBoard.GameBoard))
+		}
+		if _, err := useCase.EndTurn(gameId, playerId, nil); err != nil {
+			t.Fatalf("Failed to end turn: %v", err)
+		}
+		game, err = useCase.GetGame(gameId)
+		if err != nil {
+			t.Fatalf("Failed to get game: %v", err)
+		}
+	}
+	if GetPlayerTurn(game.ProcessedGameBoard.GameBoard) != "red" {
+		t.Errorf("Expected the turn of red, got %s", GetPlayerTurn(game.ProcessedGameBoard.GameBoard))
+	}
+
+	if _, err = useCase.AcceptDraw(gameId, "blue", nil); err == nil {
+		t.Errorf("Accepted a draw that was never offered")
+	}
+	if _, err = useCase.OfferDraw(gameId, "red", nil); err != nil {
+		t.Fatalf("Failed to offer a draw: %v", err)
+	}
+	if _, err = useCase.AcceptDraw(gameId, "blue", nil); err == nil {
+		t.Errorf("A player accepted the draw offered by their team")
+	}
+
+	// a player who resigns makes their whole team lose
+	result, err := useCase.Resign(gameId, "blue", nil)
+	if err != nil {
+		t.Fatalf("Failed to resign: %v", err)
+	}
+	if result.Winner != "team:2" {
+		t.Errorf("Expected the second team to win, got %s", result.Winner)
+	}
+
+	for playerId, wins := range map[string]int{"red": 0, "blue": 0, "green": 1, "yellow": 1} {
+		stats, err := useCase.GetPlayerStats(playerId)
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		if stats.Games != 1 || stats.Wins != wins {
+			t.Errorf("Wrong stats for %s %+v", playerId, stats)
+		}
+	}
+}
@@ -2,7 +2,11 @@ package gamemechanics
 
 import (
 	"crypto/md5"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"math/rand"
 	"strconv"
 )
@@ -12,41 +16,204 @@ type VarianceFactory interface {
 	GenerateDeflectionSource(gameBoard GameBoard, turn int) DirectedPosition
 }
 
-type RandomVarianceFactory struct{}
+// every game stores the version of the variance algorithm it was created with,
+// an algorithm must never change once registered, otherwise the stored games replay differently
+const (
+	LEGACY_VARIANCE_VERSION  = 1
+	CURRENT_VARIANCE_VERSION = 2
+)
 
-func (factory RandomVarianceFactory) GeneratePawnVariant(str string, turns int) []string {
+var varianceFactories = map[int]VarianceFactory{
+	LEGACY_VARIANCE_VERSION:  RandomVarianceFactory{},
+	CURRENT_VARIANCE_VERSION: HashVarianceFactory{},
+}
+
+// newGameSeed is stored with a new game, it must not be guessable
+// otherwise the players could predict their next variants
+func newGameSeed() (string, error) {
+	seed := make([]byte, 16)
+	if _, err := cryptorand.Read(seed); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(seed), nil
+}
+
+// VariantWeight is the chance of a pawn variant to be generated, the weights of a game add up to 1
+type VariantWeight struct {
+	Name   string
+	Weight float64
+}
+
+// getVariantWeights splits what is left after the blockers, the splitters and the portals evenly between the two deflecting pawns,
+// without them a roll under 0.5 is a slash like it always was
+func getVariantWeights(blockerChance int, splitterChance int, portalChance int) []VariantWeight {
+	blockerWeight := float64(blockerChance) / 100
+	splitterWeight := float64(splitterChance) / 100
+	portalWeight := float64(portalChance) / 100
+	weights := []VariantWeight{
+		{Name: SLASH, Weight: (1 - blockerWeight - splitterWeight - portalWeight) / 2},
+		{Name: BACKSLASH, Weight: (1 - blockerWeight - splitterWeight - portalWeight) / 2},
+	}
+	if blockerChance > 0 {
+		weights = append(weights, VariantWeight{Name: BLOCKER, Weight: blockerWeight})
+	}
+	if splitterChance > 0 {
+		weights = append(weights, VariantWeight{Name: SPLITTER, Weight: splitterWeight})
+	}
+	if portalChance > 0 {
+		weights = append(weights, VariantWeight{Name: PORTAL, Weight: portalWeight})
+	}
+	return weights
+}
+
+func pickVariant(weights []VariantWeight, roll float64) string {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight.Weight
+		if roll < total {
+			return weight.Name
+		}
+	}
+	return weights[len(weights)-1].Name
+}
+
+// pickDeflectionSource fires the deflector vertically between the goals of a game with two sides, like it always did.
+// With more sides it is fired from any of the four edges, so that each goal is as likely to be in the way of the deflector
+func pickDeflectionSource(defenition GameBoardDefenition, roll float64) DirectedPosition {
+	sources := []DirectedPosition{
+		{Position: position(defenition.XMax/2, defenition.YMax+1), Direction: DOWN},
+		{Position: position(defenition.XMax/2, -1), Direction: UP},
+	}
+	if len(getSides(defenition)) > 2 {
+		sources = append(sources,
+			DirectedPosition{Position: position(-1, defenition.YMax/2), Direction: RIGHT},
+			DirectedPosition{Position: position(defenition.XMax+1, defenition.YMax/2), Direction: LEFT},
+		)
+	}
+	return sources[int(roll*float64(len(sources)))]
+}
+
+// weightedVarianceFactory is a factory that can generate the variants with other weights than the default ones
+type weightedVarianceFactory interface {
+	withVariantWeights(weights []VariantWeight) VarianceFactory
+}
+
+// getVarianceFactory is the factory of the variance version of the game, with the weights of its rules
+func getVarianceFactory(defenition GameBoardDefenition) (VarianceFactory, error) {
+	factory, err := GetVarianceFactory(defenition.VarianceVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if weighted, ok := factory.(weightedVarianceFactory); ok && (defenition.BlockerChance > 0 || defenition.SplitterChance > 0 || defenition.PortalChance > 0) {
+		return weighted.withVariantWeights(getVariantWeights(defenition.BlockerChance, defenition.SplitterChance, defenition.PortalChance)), nil
+	}
+	return factory, nil
+}
+
+func GetVarianceFactory(version int) (VarianceFactory, error) {
+	// games that were stored before the version was recorded
+	if version == 0 {
+		version = LEGACY_VARIANCE_VERSION
+	}
+
+	factory, ok := varianceFactories[version]
+	if !ok {
+		return nil, errors.New("unknown variance version " + strconv.Itoa(version))
+	}
+	return factory, nil
+}
+
+// RandomVarianceFactory is the version 1 algorithm, it depends on the math/rand implementation.
+// Without weights it only generates slashes and backslashes
+type RandomVarianceFactory struct {
+	weights []VariantWeight
+}
+
+func (factory RandomVarianceFactory) withVariantWeights(weights []VariantWeight) VarianceFactory {
+	factory.weights = weights
+	return factory
+}
+
+// newSeededRand gives each game and player a private generator,
+// seeding the shared math/rand source would let concurrent games corrupt each other's sequences
+func newSeededRand(str string) *rand.Rand {
 	hashGen := md5.New()
 	hashGen.Write([]byte(str))
 	var seed uint64 = binary.BigEndian.Uint64(hashGen.Sum(nil))
-	rand.Seed(int64(seed))
+	return rand.New(rand.NewSource(int64(seed)))
+}
+
+func (factory RandomVarianceFactory) GeneratePawnVariant(str string, turns int) []string {
+	generator := newSeededRand(str)
+
+	weights := factory.weights
+	if weights == nil {
+		weights = getVariantWeights(0, 0, 0)
+	}
 
 	variants := make([]string, turns)
 	for i := 0; i < turns; i++ {
-		rand := rand.Float64()
-		if rand < 0.5 {
-			variants[i] = SLASH
-		} else {
-			variants[i] = BACKSLASH
-		}
+		variants[i] = pickVariant(weights, generator.Float64())
 	}
 	return variants
 }
 
 func (factory RandomVarianceFactory) GenerateDeflectionSource(gameBoard GameBoard, turn int) DirectedPosition {
-	hashGen := md5.New()
-	hashGen.Write([]byte(strconv.Itoa(turn) + gameBoard.defenition.Id))
-	var seed uint64 = binary.BigEndian.Uint64(hashGen.Sum(nil))
-	rand.Seed(int64(seed))
+	generator := newSeededRand(strconv.Itoa(turn) + getVarianceSeed(gameBoard.defenition))
+	return pickDeflectionSource(gameBoard.defenition, generator.Float64())
+}
 
-	if rand.Float64() < 0.5 {
-		return DirectedPosition{
-			Position:  position(gameBoard.defenition.XMax/2, gameBoard.defenition.YMax+1),
-			Direction: DOWN,
-		}
-	} else {
-		return DirectedPosition{
-			Position:  position(gameBoard.defenition.XMax/2, -1),
-			Direction: UP,
-		}
+// HashVarianceFactory is the version 2 algorithm, it only depends on sha256 and its own generator
+// so that changes to math/rand can never alter the stored games
+type HashVarianceFactory struct {
+	weights []VariantWeight
+}
+
+func (factory HashVarianceFactory) withVariantWeights(weights []VariantWeight) VarianceFactory {
+	factory.weights = weights
+	return factory
+}
+
+type splitMix64 struct {
+	state uint64
+}
+
+func newSplitMix64(str string) *splitMix64 {
+	hash := sha256.Sum256([]byte(str))
+	return &splitMix64{
+		state: binary.BigEndian.Uint64(hash[:8]),
 	}
 }
+
+func (generator *splitMix64) next() uint64 {
+	generator.state += 0x9e3779b97f4a7c15
+	z := generator.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (generator *splitMix64) Float64() float64 {
+	return float64(generator.next()>>11) / (1 << 53)
+}
+
+func (factory HashVarianceFactory) GeneratePawnVariant(str string, turns int) []string {
+	generator := newSplitMix64(str)
+
+	weights := factory.weights
+	if weights == nil {
+		weights = getVariantWeights(0, 0, 0)
+	}
+
+	variants := make([]string, turns)
+	for i := 0; i < turns; i++ {
+		variants[i] = pickVariant(weights, generator.Float64())
+	}
+	return variants
+}
+
+func (factory HashVarianceFactory) GenerateDeflectionSource(gameBoard GameBoard, turn int) DirectedPosition {
+	generator := newSplitMix64(getVarianceSeed(gameBoard.defenition) + ":" + strconv.Itoa(turn))
+	return pickDeflectionSource(gameBoard.defenition, generator.Float64())
+}
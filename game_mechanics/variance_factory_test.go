@@ -0,0 +1,246 @@
+package gamemechanics
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestRandomVarianceFactoryIsStable(t *testing.T) {
+	factory := RandomVarianceFactory{}
+
+	// generated by the original global rand.Seed implementation, stored games depend on them
+	expectedVariants := map[string][]string{
+		"62a0c1f2e4b0a1b2c3d4e5f6red":  {BACKSLASH, BACKSLASH, SLASH, SLASH, BACKSLASH, BACKSLASH, BACKSLASH, SLASH, BACKSLASH, SLASH},
+		"62a0c1f2e4b0a1b2c3d4e5f6blue": {SLASH, SLASH, BACKSLASH, SLASH, BACKSLASH, BACKSLASH, BACKSLASH, BACKSLASH, BACKSLASH, BACKSLASH},
+	}
+	for digest, expected := range expectedVariants {
+		variants := factory.GeneratePawnVariant(digest, len(expected))
+		if !reflect.DeepEqual(variants, expected) {
+			t.Errorf("Variants changed for %s, got %v", digest, variants)
+		}
+	}
+
+	gameBoard := GameBoard{
+		defenition: GameBoardDefenition{Id: "62a0c1f2e4b0a1b2c3d4e5f6", XMax: 2, YMax: 2},
+	}
+	expectedDirections := []int{UP, UP, DOWN, DOWN, UP, DOWN, UP, UP, DOWN, DOWN}
+	for turn, expected := range expectedDirections {
+		source := factory.GenerateDeflectionSource(gameBoard, turn)
+		if source.Direction != expected {
+			t.Errorf("Deflection source changed for turn %d", turn)
+		}
+	}
+}
+
+func TestHashVarianceFactoryIsStable(t *testing.T) {
+	factory := HashVarianceFactory{}
+
+	expected := []string{SLASH, BACKSLASH, SLASH, SLASH, SLASH, SLASH, BACKSLASH, SLASH, SLASH, SLASH}
+	variants := factory.GeneratePawnVariant("62a0c1f2e4b0a1b2c3d4e5f6red", len(expected))
+	if !reflect.DeepEqual(variants, expected) {
+		t.Errorf("Variants changed, got %v", variants)
+	}
+
+	gameBoard := GameBoard{
+		defenition: GameBoardDefenition{Id: "62a0c1f2e4b0a1b2c3d4e5f6", XMax: 2, YMax: 2},
+	}
+	expectedDirections := []int{UP, UP, UP, UP, DOWN, DOWN, UP, DOWN, UP, UP}
+	for turn, expected := range expectedDirections {
+		source := factory.GenerateDeflectionSource(gameBoard, turn)
+		if source.Direction != expected {
+			t.Errorf("Deflection source changed for turn %d", turn)
+		}
+	}
+}
+
+func TestGameReplaysWithItsVarianceVersion(t *testing.T) {
+	expectedFactories := map[int]string{
+		0:                        "RandomVarianceFactory",
+		LEGACY_VARIANCE_VERSION:  "RandomVarianceFactory",
+		CURRENT_VARIANCE_VERSION: "HashVarianceFactory",
+	}
+	for version, expectedFactory := range expectedFactories {
+		defenition := newReplayDefenition("62a0c1f2e4b0a1b2c3d4e5f6")
+		defenition.VarianceVersion = version
+
+		processedGameBoard, err := NewGameBoard(defenition)
+		if err != nil {
+			t.Fatalf("Failed to replay version %d: %v", version, err)
+		}
+
+		factory := reflect.TypeOf(processedGameBoard.VarianceFactory).Name()
+		if factory != expectedFactory {
+			t.Errorf("Version %d was replayed with %s", version, factory)
+		}
+	}
+
+	defenition := newReplayDefenition("62a0c1f2e4b0a1b2c3d4e5f6")
+	defenition.VarianceVersion = 999
+	if _, err := NewGameBoard(defenition); err == nil {
+		t.Errorf("Replayed a game with an unknown variance version")
+	}
+}
+
+func newReplayDefenition(gameId string) GameBoardDefenition {
+	playerIds := []string{"red", "blue"}
+	positions := []Position{position(0, 0), position(1, 1), position(2, 2), position(0, 2), position(2, 0), position(1, 0)}
+	events := make([]GameEvent, 0)
+	for turn, pos := range positions {
+		player := playerIds[turn%2]
+		events = append(events,
+			NewSkipPawnEvent(player),
+			NewCreatePawnEvent(pos, player),
+			NewFireDeflectorEvent(),
+			NewEndTurnEvent(player),
+		)
+	}
+
+	return GameBoardDefenition{
+		Id:              gameId,
+		PlayerIds:       playerIds,
+		YMax:            2,
+		XMax:            2,
+		TargetScore:     6,
+		TimePerTurn:     45 * 1000,
+		PawnDurability:  5,
+		ShufflesPerTurn: 1,
+		Events:          events,
+	}
+}
+
+func TestConcurrentReplaysAreDeterministic(t *testing.T) {
+	gameCount := 32
+	defenitions := make([]GameBoardDefenition, gameCount)
+	expected := make([]map[string]interface{}, gameCount)
+	for i := 0; i < gameCount; i++ {
+		defenitions[i] = newReplayDefenition("game" + strconv.Itoa(i))
+		processedGameBoard, err := NewGameBoard(defenitions[i])
+		if err != nil {
+			t.Fatalf("Failed to replay game %d: %v", i, err)
+		}
+		expected[i] = processedGameBoard.toMap()
+	}
+
+	var wg sync.WaitGroup
+	for round := 0; round < 8; round++ {
+		for i := 0; i < gameCount; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				processedGameBoard, err := NewGameBoard(defenitions[i])
+				if err != nil {
+					t.Errorf("Failed to replay game %d: %v", i, err)
+					return
+				}
+				if !reflect.DeepEqual(processedGameBoard.toMap(), expected[i]) {
+					t.Errorf("Concurrent replay of game %d is different", i)
+				}
+			}(i)
+		}
+	}
+	wg.Wait()
+}
+
+func TestNewGamesHaveTheirOwnSeed(t *testing.T) {
+	useCase := newTestUseCase(t)
+
+	seeds := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		gameId, err := useCase.CreateNewGame([]string{"red", "blue"}, getStandardRules())
+		if err != nil {
+			t.Fatalf("Failed to create game: %v", err)
+		}
+
+		game, err := useCase.GetGame(gameId)
+		if err != nil {
+			t.Fatalf("Failed to get game: %v", err)
+		}
+		defenition := game.ProcessedGameBoard.GameBoard.defenition
+		if len(defenition.Seed) != 32 || seeds[defenition.Seed] {
+			t.Fatalf("Expected a new random seed, got %q", defenition.Seed)
+		}
+		seeds[defenition.Seed] = true
+
+		// the variants do not depend on the id the game got from the storage
+		defenition.Id = "another id"
+		processedGameBoard, err := NewGameBoard(defenition)
+		if err != nil {
+			t.Fatalf("Failed to replay game: %v", err)
+		}
+		if !reflect.DeepEqual(processedGameBoard.PawnVariants, game.ProcessedGameBoard.PawnVariants) {
+			t.Errorf("The variants changed with the id of the game")
+		}
+	}
+}
+
+func TestBlockerAndSplitterChance(t *testing.T) {
+	for _, version := range []int{LEGACY_VARIANCE_VERSION, CURRENT_VARIANCE_VERSION} {
+		defenition := newReplayDefenition("62a0c1f2e4b0a1b2c3d4e5f6")
+		defenition.VarianceVersion = version
+
+		factory, err := getVarianceFactory(defenition)
+		if err != nil {
+			t.Fatalf("Failed to get factory: %v", err)
+		}
+		defaultFactory, err := GetVarianceFactory(version)
+		if err != nil {
+			t.Fatalf("Failed to get factory: %v", err)
+		}
+		// the games without blockers keep the variants they always had
+		if !reflect.DeepEqual(factory.GeneratePawnVariant("red", 100), defaultFactory.GeneratePawnVariant("red", 100)) {
+			t.Errorf("The variants of version %d changed without blockers", version)
+		}
+
+		defenition.BlockerChance = 20
+		factory, err = getVarianceFactory(defenition)
+		if err != nil {
+			t.Fatalf("Failed to get factory: %v", err)
+		}
+		blockers := 0
+		for _, variant := range factory.GeneratePawnVariant("red", 1000) {
+			if variant == BLOCKER {
+				blockers += 1
+			}
+		}
+		if blockers < 150 || blockers > 250 {
+			t.Errorf("Expected around 200 blockers in version %d, got %d", version, blockers)
+		}
+
+		defenition.SplitterChance = 20
+		factory, err = getVarianceFactory(defenition)
+		if err != nil {
+			t.Fatalf("Failed to get factory: %v", err)
+		}
+		splitters := 0
+		for _, variant := range factory.GeneratePawnVariant("red", 1000) {
+			if variant == SPLITTER {
+				splitters += 1
+			}
+		}
+		if splitters < 150 || splitters > 250 {
+			t.Errorf("Expected around 200 splitters in version %d, got %d", version, splitters)
+		}
+	}
+}
+
+func TestDeflectionSources(t *testing.T) {
+	twoPlayers := NewGameBoardDefinition("", []string{"red", "blue"})
+	if source := pickDeflectionSource(twoPlayers, 0.1); source.Direction != DOWN {
+		t.Errorf("Expected the deflector to be fired down, got %v", source)
+	}
+	if source := pickDeflectionSource(twoPlayers, 0.9); source.Direction != UP {
+		t.Errorf("Expected the deflector to be fired up, got %v", source)
+	}
+
+	// with more players the deflector is fired towards every goal in turn
+	fourPlayers := NewGameBoardDefinition("", []string{"red", "blue", "green", "yellow"})
+	directions := make([]int, 0)
+	for _, roll := range []float64{0.1, 0.3, 0.6, 0.9} {
+		directions = append(directions, pickDeflectionSource(fourPlayers, roll).Direction)
+	}
+	if !reflect.DeepEqual(directions, []int{DOWN, UP, RIGHT, LEFT}) {
+		t.Errorf("Expected the deflector to be fired from all the edges, got %v", directions)
+	}
+}
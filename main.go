@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"os"
 	gamemechanics "projectdeflector/game/game_mechanics"
+	"strconv"
+	"time"
 
 	"projectdeflector/game/repositories"
 
@@ -22,10 +25,13 @@ func main() {
 		log.Fatalf("could not load env vars ")
 	}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		ErrorHandler: errorHandler,
+	})
 	app.Use(recover.New())
 
 	repoFactory := repositories.GetRepositoryFactory()
+	go expireTurns(repoFactory)
 
 	app.Use("/", func(c *fiber.Ctx) error {
 		repo, cleanup, err := repoFactory.GetRepository()
@@ -83,6 +89,68 @@ func main() {
 		return c.JSON(processedGameBoard.ToMap())
 	})
 
+	app.Get("/game/:id/at/:eventIndex", func(c *fiber.Ctx) error {
+		gameId := c.Params("id")
+		eventIndex, err := strconv.Atoi(c.Params("eventIndex"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "eventIndex must be a number")
+		}
+
+		repo := c.Locals("repo").(repositories.Repository)
+		useCase := gamemechanics.UseCase{
+			Repo: repo,
+		}
+
+		processedGameBoard, err := useCase.GetGameAt(gameId, eventIndex)
+
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(processedGameBoard.ToMap())
+	})
+
+	app.Get("/game/:id/replay", func(c *fiber.Ctx) error {
+		gameId := c.Params("id")
+
+		repo := c.Locals("repo").(repositories.Repository)
+		useCase := gamemechanics.UseCase{
+			Repo: repo,
+		}
+
+		replay, err := useCase.GetGameReplay(gameId)
+
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(replay.ToMap())
+	})
+
+	app.Post("/game/:id/fork", func(c *fiber.Ctx) error {
+		gameId := c.Params("id")
+		payload := struct {
+			EventIndex int `json:"eventIndex"`
+		}{}
+		if err := c.BodyParser(&payload); err != nil {
+			return err
+		}
+
+		repo := c.Locals("repo").(repositories.Repository)
+		useCase := gamemechanics.UseCase{
+			Repo: repo,
+		}
+
+		forkId, err := useCase.ForkGame(gameId, payload.EventIndex)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"gameId": forkId,
+		})
+	})
+
 	app.Post("/stats/game", func(c *fiber.Ctx) error {
 		playerId := c.Locals("userId").(string)
 
@@ -100,19 +168,36 @@ func main() {
 
 	app.Post("/internal/game", func(c *fiber.Ctx) error {
 		payload := struct {
-			PlayerIds []string `json:"playerIds"`
+			PlayerIds []string      `json:"playerIds"`
+			Teams     [][]string    `json:"teams"`
+			Preset    string        `json:"preset"`
+			Rules     *rulesPayload `json:"rules"`
 		}{}
 
 		if err := c.BodyParser(&payload); err != nil {
 			return err
 		}
 
+		// the rules sent with the game take the place of the preset
+		var rules gamemechanics.GameRules
+		var err error
+		if payload.Rules != nil {
+			rules = payload.Rules.toGameRules()
+		} else if rules, err = gamemechanics.GetGameRulesPreset(payload.Preset); err != nil {
+			return err
+		}
+
 		repo := c.Locals("repo").(repositories.Repository)
 		useCase := gamemechanics.UseCase{
 			Repo: repo,
 		}
 
-		gameId, err := useCase.CreateNewGame(payload.PlayerIds)
+		var gameId string
+		if len(payload.Teams) > 0 {
+			gameId, err = useCase.CreateNewTeamGame(payload.Teams, rules)
+		} else {
+			gameId, err = useCase.CreateNewGame(payload.PlayerIds, rules)
+		}
 		if err != nil {
 			return err
 		}
@@ -129,6 +214,11 @@ func main() {
 			GameId string `json:"gameId"`
 			X      int    `json:"x"`
 			Y      int    `json:"y"`
+			Twin   *struct {
+				X int `json:"x"`
+				Y int `json:"y"`
+			} `json:"twin"`
+			EventCount *int `json:"eventCount"`
 		}{}
 		if err := c.BodyParser(&payload); err != nil {
 			return err
@@ -139,10 +229,18 @@ func main() {
 			Repo: repo,
 		}
 
+		var twin *gamemechanics.Position
+		if payload.Twin != nil {
+			twinPosition := gamemechanics.NewPosition(payload.Twin.X, payload.Twin.Y)
+			twin = &twinPosition
+		}
+
 		result, err := useCase.AddPawn(payload.GameId, gamemechanics.AddPawnRequest{
-			X:          payload.X,
-			Y:          payload.Y,
-			PlayerSide: playerId,
+			X:                  payload.X,
+			Y:                  payload.Y,
+			PlayerSide:         playerId,
+			Twin:               twin,
+			ExpectedEventCount: payload.EventCount,
 		})
 
 		if err != nil {
@@ -152,10 +250,35 @@ func main() {
 		return c.JSON(result.ToMap())
 	})
 
+	app.Post("/pawn/undo", func(c *fiber.Ctx) error {
+		playerId := c.Locals("userId").(string)
+		payload := struct {
+			GameId     string `json:"gameId"`
+			EventCount *int   `json:"eventCount"`
+		}{}
+		if err := c.BodyParser(&payload); err != nil {
+			return err
+		}
+
+		repo := c.Locals("repo").(repositories.Repository)
+		useCase := gamemechanics.UseCase{
+			Repo: repo,
+		}
+
+		result, err := useCase.UndoPawn(payload.GameId, playerId, payload.EventCount)
+
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(result.ToMap())
+	})
+
 	app.Post("/turn", func(c *fiber.Ctx) error {
 		playerId := c.Locals("userId").(string)
 		payload := struct {
-			GameId string `json:"gameId"`
+			GameId     string `json:"gameId"`
+			EventCount *int   `json:"eventCount"`
 		}{}
 		if err := c.BodyParser(&payload); err != nil {
 			return err
@@ -166,7 +289,7 @@ func main() {
 			Repo: repo,
 		}
 
-		result, err := useCase.EndTurn(payload.GameId, playerId)
+		result, err := useCase.EndTurn(payload.GameId, playerId, payload.EventCount)
 
 		if err != nil {
 			return err
@@ -199,10 +322,83 @@ func main() {
 		return c.JSON(result.ToMap())
 	})
 
+	app.Post("/resign", func(c *fiber.Ctx) error {
+		playerId := c.Locals("userId").(string)
+		payload := struct {
+			GameId     string `json:"gameId"`
+			EventCount *int   `json:"eventCount"`
+		}{}
+		if err := c.BodyParser(&payload); err != nil {
+			return err
+		}
+
+		repo := c.Locals("repo").(repositories.Repository)
+		useCase := gamemechanics.UseCase{
+			Repo: repo,
+		}
+
+		result, err := useCase.Resign(payload.GameId, playerId, payload.EventCount)
+
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(result.ToMap())
+	})
+
+	app.Post("/draw/offer", func(c *fiber.Ctx) error {
+		playerId := c.Locals("userId").(string)
+		payload := struct {
+			GameId     string `json:"gameId"`
+			EventCount *int   `json:"eventCount"`
+		}{}
+		if err := c.BodyParser(&payload); err != nil {
+			return err
+		}
+
+		repo := c.Locals("repo").(repositories.Repository)
+		useCase := gamemechanics.UseCase{
+			Repo: repo,
+		}
+
+		result, err := useCase.OfferDraw(payload.GameId, playerId, payload.EventCount)
+
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(result.ToMap())
+	})
+
+	app.Post("/draw/accept", func(c *fiber.Ctx) error {
+		playerId := c.Locals("userId").(string)
+		payload := struct {
+			GameId     string `json:"gameId"`
+			EventCount *int   `json:"eventCount"`
+		}{}
+		if err := c.BodyParser(&payload); err != nil {
+			return err
+		}
+
+		repo := c.Locals("repo").(repositories.Repository)
+		useCase := gamemechanics.UseCase{
+			Repo: repo,
+		}
+
+		result, err := useCase.AcceptDraw(payload.GameId, playerId, payload.EventCount)
+
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(result.ToMap())
+	})
+
 	app.Post("/shuffle", func(c *fiber.Ctx) error {
 		playerId := c.Locals("userId").(string)
 		payload := struct {
-			GameId string `json:"gameId"`
+			GameId     string `json:"gameId"`
+			EventCount *int   `json:"eventCount"`
 		}{}
 		if err := c.BodyParser(&payload); err != nil {
 			return err
@@ -213,7 +409,7 @@ func main() {
 			Repo: repo,
 		}
 
-		result, err := useCase.Shuffle(payload.GameId, playerId)
+		result, err := useCase.Shuffle(payload.GameId, playerId, payload.EventCount)
 
 		if err != nil {
 			return err
@@ -253,3 +449,102 @@ func main() {
 
 	log.Fatal(app.Listen(":3000"))
 }
+
+// rulesPayload replaces the whole preset, all the durations are in milliseconds
+type rulesPayload struct {
+	XMax            int `json:"xMax"`
+	YMax            int `json:"yMax"`
+	TargetScore     int `json:"targetScore"`
+	TimePerTurn     int `json:"timePerTurn"`
+	PawnDurability  int `json:"pawnDurability"`
+	ShufflesPerTurn int `json:"shufflesPerTurn"`
+	UndosPerTurn    int `json:"undosPerTurn"`
+	MaxTimeouts     int `json:"maxTimeouts"`
+	BlockerChance   int `json:"blockerChance"`
+	SplitterChance  int `json:"splitterChance"`
+	PortalChance    int `json:"portalChance"`
+	TimeControl     struct {
+		Bank      int64 `json:"bank"`
+		Increment int64 `json:"increment"`
+		Delay     int64 `json:"delay"`
+	} `json:"timeControl"`
+}
+
+func (payload rulesPayload) toGameRules() gamemechanics.GameRules {
+	return gamemechanics.GameRules{
+		XMax:            payload.XMax,
+		YMax:            payload.YMax,
+		TargetScore:     payload.TargetScore,
+		TimePerTurn:     payload.TimePerTurn,
+		PawnDurability:  payload.PawnDurability,
+		ShufflesPerTurn: payload.ShufflesPerTurn,
+		UndosPerTurn:    payload.UndosPerTurn,
+		MaxTimeouts:     payload.MaxTimeouts,
+		BlockerChance:   payload.BlockerChance,
+		SplitterChance:  payload.SplitterChance,
+		PortalChance:    payload.PortalChance,
+		TimeControl: gamemechanics.TimeControl{
+			Bank:      payload.TimeControl.Bank,
+			Increment: payload.TimeControl.Increment,
+			Delay:     payload.TimeControl.Delay,
+		},
+	}
+}
+
+const turnExpiryInterval = time.Second
+
+// expireTurns ends the turns that ran out of time, so that a game moves forward
+// even when none of its players is connected to call /turn/expire
+func expireTurns(repoFactory repositories.RepositoryFactory) {
+	ticker := time.NewTicker(turnExpiryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		repo, cleanup, err := repoFactory.GetRepository()
+		if err != nil {
+			log.Printf("could not get the repository to expire turns: %v", err)
+			continue
+		}
+
+		useCase := gamemechanics.UseCase{
+			Repo: repo,
+		}
+		if _, err := useCase.ExpireTurns(); err != nil {
+			log.Printf("could not expire turns: %v", err)
+		}
+		cleanup()
+	}
+}
+
+func errorHandler(c *fiber.Ctx, err error) error {
+	var conflict gamemechanics.EventCountConflictError
+	if errors.As(err, &conflict) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":      err.Error(),
+			"eventCount": conflict.EventCount,
+		})
+	}
+
+	var outOfRange gamemechanics.EventIndexOutOfRangeError
+	if errors.As(err, &outOfRange) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      err.Error(),
+			"eventCount": outOfRange.EventCount,
+		})
+	}
+
+	var invalidRules gamemechanics.GameRulesError
+	if errors.As(err, &invalidRules) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if errors.Is(err, repositories.ErrEventCountMismatch) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return fiber.DefaultErrorHandler(c, err)
+}
@@ -9,6 +9,8 @@ type GameEndUserUpdate struct {
 	PlayerId string `json:"playerId"`
 	Games    int    `json:"games"`
 	Wins     int    `json:"wins"`
+	Draws    int    `json:"draws"`
+	Losses   int    `json:"losses"`
 }
 
 func NotifyUserServiceGameEnd(updates []GameEndUserUpdate) {
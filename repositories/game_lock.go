@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrGameLocked = errors.New("the game is locked")
+
+// a lock is a lease, if its owner does not release it in time
+// it means that something went wrong and it will no longer be locked
+var gameLockLease = 5 * time.Second
+
+var (
+	lockRetryDelay    = 25 * time.Millisecond
+	lockMaxRetryDelay = 400 * time.Millisecond
+	lockWaitTimeout   = 2 * time.Second
+)
+
+// LockGame takes the lock of a game under a new owner token. While another owner holds the lock,
+// it retries with an exponential backoff until lockWaitTimeout runs out.
+// The returned release function only frees the lock if it is still owned by this call, and is safe to call more than once
+func LockGame(repo Repository, id string) (GetGameBoardDefenitionResult, func(), error) {
+	noop := func() {}
+
+	lockToken, err := newLockToken()
+	if err != nil {
+		return GetGameBoardDefenitionResult{}, noop, err
+	}
+
+	delay := lockRetryDelay
+	deadline := time.Now().Add(lockWaitTimeout)
+	for {
+		result, err := repo.GetGameAndLock(id, lockToken)
+		if err == nil {
+			return result, newLockRelease(repo, id, lockToken), nil
+		}
+
+		if !errors.Is(err, ErrGameLocked) || time.Now().Add(delay).After(deadline) {
+			return GetGameBoardDefenitionResult{}, noop, err
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+		if delay > lockMaxRetryDelay {
+			delay = lockMaxRetryDelay
+		}
+	}
+}
+
+// TryLockGame takes the lock of a game only if nobody holds it, it returns ErrGameLocked right away otherwise
+func TryLockGame(repo Repository, id string) (GetGameBoardDefenitionResult, func(), error) {
+	noop := func() {}
+
+	lockToken, err := newLockToken()
+	if err != nil {
+		return GetGameBoardDefenitionResult{}, noop, err
+	}
+
+	result, err := repo.GetGameAndLock(id, lockToken)
+	if err != nil {
+		return GetGameBoardDefenitionResult{}, noop, err
+	}
+	return result, newLockRelease(repo, id, lockToken), nil
+}
+
+// newLockRelease frees the lock on the first call only, so it can be called as soon as the work is saved
+// and still be deferred for the other exit paths
+func newLockRelease(repo Repository, id string, lockToken string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			repo.UnlockGame(id, lockToken)
+		})
+	}
+}
+
+func newLockToken() (string, error) {
+	bytes := make([]byte, 16)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
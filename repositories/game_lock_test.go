@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLockGameWaitsForRelease(t *testing.T) {
+	repo := NewMemoryRepository()
+	id := insertTestGame(t, repo, []string{"red", "blue"}, "", time.Now().UnixMilli())
+
+	_, release, err := LockGame(repo, id)
+	if err != nil {
+		t.Fatalf("Failed to lock game: %v", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		release()
+	}()
+
+	game, releaseAgain, err := LockGame(repo, id)
+	if err != nil || game.Id != id {
+		t.Fatalf("Failed to wait for the lock: %v", err)
+	}
+
+	// releasing an outdated lock is a no-op
+	release()
+	if _, err := repo.GetGameAndLock(id, "intruder"); err != ErrGameLocked {
+		t.Errorf("Expected the game to be locked, got %v", err)
+	}
+
+	releaseAgain()
+	if _, err := repo.GetGameAndLock(id, "next owner"); err != nil {
+		t.Errorf("Failed to lock a released game: %v", err)
+	}
+}
+
+func TestLockGameTimesOut(t *testing.T) {
+	repo := NewMemoryRepository()
+	id := insertTestGame(t, repo, []string{"red", "blue"}, "", time.Now().UnixMilli())
+
+	waitTimeout := lockWaitTimeout
+	lockWaitTimeout = 200 * time.Millisecond
+	defer func() { lockWaitTimeout = waitTimeout }()
+
+	if _, err := repo.GetGameAndLock(id, "owner"); err != nil {
+		t.Fatalf("Failed to lock game: %v", err)
+	}
+
+	start := time.Now()
+	if _, _, err := LockGame(repo, id); err != ErrGameLocked {
+		t.Errorf("Expected the game to be locked, got %v", err)
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Errorf("Did not retry before giving up")
+	}
+
+	if _, _, err := LockGame(repo, primitive.NewObjectID().Hex()); err == nil || err == ErrGameLocked {
+		t.Errorf("Expected a missing game error, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package repositories
+
+// GameSnapshot is the processed state of a game after its first EventCount events,
+// it is stored alongside the events so that loading a game only replays the events after it
+type GameSnapshot struct {
+	Version             int                 `bson:"version"`
+	EventCount          int                 `bson:"event_count"`
+	Turn                int                 `bson:"turn"`
+	Pawns               []SnapshotPawn      `bson:"pawns"`
+	ScoreBoard          map[string]int      `bson:"score_board"`
+	PawnVariants        map[string][]string `bson:"pawn_variants"`
+	AvailableShuffles   map[string]int      `bson:"available_shuffles"`
+	PlayersInMatchPoint map[string]bool     `bson:"players_in_match_point"`
+	GameInProgress      bool                `bson:"game_in_progress"`
+	Winner              string              `bson:"winner"`
+	LastTurnEndTime     int64               `bson:"last_turn_end_time"`
+	AvailableUndos      map[string]int      `bson:"available_undos"`
+	UndoablePlacements  []SnapshotPlacement `bson:"undoable_placements"`
+	DrawOfferedBy       string              `bson:"draw_offered_by"`
+	IsDraw              bool                `bson:"is_draw"`
+	ConsecutiveTimeouts map[string]int      `bson:"consecutive_timeouts"`
+	EndReason           string              `bson:"end_reason"`
+	Clocks              map[string]int64    `bson:"clocks"`
+	EliminatedPlayers   map[string]bool     `bson:"eliminated_players"`
+}
+
+type SnapshotPawn struct {
+	X           int    `bson:"x"`
+	Y           int    `bson:"y"`
+	Name        string `bson:"name"`
+	TurnPlaced  int    `bson:"turn_placed"`
+	Durability  int    `bson:"durability"`
+	PlayerOwner string `bson:"player_owner"`
+	// Twin is nil for the pawns that are not linked portals
+	Twin *SnapshotPosition `bson:"twin"`
+}
+
+type SnapshotPosition struct {
+	X int `bson:"x"`
+	Y int `bson:"y"`
+}
+
+type SnapshotPlacement struct {
+	X            int           `bson:"x"`
+	Y            int           `bson:"y"`
+	ReplacedPawn *SnapshotPawn `bson:"replaced_pawn"`
+}
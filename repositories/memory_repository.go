@@ -0,0 +1,305 @@
+package repositories
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MemoryRepository keeps the games as bson documents so that the decoded
+// values (int32, int64, ...) are the same as the ones read from mongo
+type MemoryRepository struct {
+	store *memoryStore
+}
+
+type memoryStore struct {
+	mutex sync.Mutex
+	ids   []string
+	games map[string][]byte
+}
+
+func NewMemoryRepository() MemoryRepository {
+	return MemoryRepository{
+		store: &memoryStore{
+			ids:   make([]string, 0),
+			games: make(map[string][]byte),
+		},
+	}
+}
+
+func (store *memoryStore) getDocument(id string) (InserGameBoardDefenition, bool, error) {
+	var document InserGameBoardDefenition
+	raw, ok := store.games[id]
+	if !ok {
+		return document, false, nil
+	}
+	err := bson.Unmarshal(raw, &document)
+	return document, true, err
+}
+
+func (store *memoryStore) getResult(id string) (GetGameBoardDefenitionResult, error) {
+	var result GetGameBoardDefenitionResult
+	raw, ok := store.games[id]
+	if !ok {
+		return GetGameBoardDefenitionResult{}, mongo.ErrNoDocuments
+	}
+	err := bson.Unmarshal(raw, &result)
+	if err != nil {
+		return GetGameBoardDefenitionResult{}, err
+	}
+	result.Id = id
+	return result, nil
+}
+
+func (store *memoryStore) setDocument(id string, document InserGameBoardDefenition) error {
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+	store.games[id] = raw
+	return nil
+}
+
+func (repo MemoryRepository) InsertGame(defenition InserGameBoardDefenition) (string, error) {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	id := primitive.NewObjectID().Hex()
+	err := repo.store.setDocument(id, defenition)
+	if err != nil {
+		return "", err
+	}
+	repo.store.ids = append(repo.store.ids, id)
+	return id, nil
+}
+
+func (repo MemoryRepository) GetGameAndLock(id string, lockToken string) (GetGameBoardDefenitionResult, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return GetGameBoardDefenitionResult{}, err
+	}
+
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	now := time.Now().UnixMilli()
+
+	document, ok, err := repo.store.getDocument(id)
+	if err != nil {
+		return GetGameBoardDefenitionResult{}, err
+	}
+	if !ok {
+		return GetGameBoardDefenitionResult{}, mongo.ErrNoDocuments
+	}
+	if document.LockUntil > now {
+		return GetGameBoardDefenitionResult{}, ErrGameLocked
+	}
+
+	result, err := repo.store.getResult(id)
+	if err != nil {
+		return GetGameBoardDefenitionResult{}, err
+	}
+
+	document.LockUntil = now + gameLockLease.Milliseconds()
+	document.LockOwner = lockToken
+	err = repo.store.setDocument(id, document)
+	if err != nil {
+		return GetGameBoardDefenitionResult{}, err
+	}
+	return result, nil
+}
+
+func (repo MemoryRepository) UnlockGame(id string, lockToken string) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return err
+	}
+
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	document, ok, err := repo.store.getDocument(id)
+	if err != nil || !ok || document.LockOwner != lockToken {
+		return err
+	}
+	document.LockUntil = 0
+	document.LockOwner = ""
+	return repo.store.setDocument(id, document)
+}
+
+func (repo MemoryRepository) GetGame(id string) (GetGameBoardDefenitionResult, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return GetGameBoardDefenitionResult{}, err
+	}
+
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	return repo.store.getResult(id)
+}
+
+func (repo MemoryRepository) GetOngoingPlayerGame(playerId string) (GetGameBoardDefenitionResult, error) {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	for _, id := range repo.store.ids {
+		document, _, err := repo.store.getDocument(id)
+		if err != nil {
+			return GetGameBoardDefenitionResult{}, err
+		}
+		if document.Winner == "" && !document.Draw && document.ForkedFrom == "" && containsPlayer(document.PlayerIds, playerId) && !containsPlayer(document.EliminatedPlayers, playerId) {
+			return repo.store.getResult(id)
+		}
+	}
+
+	return GetGameBoardDefenitionResult{}, mongo.ErrNoDocuments
+}
+
+func (repo MemoryRepository) ReplaceGame(id string, defenition InserGameBoardDefenition) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return err
+	}
+
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	if _, ok := repo.store.games[id]; !ok {
+		return nil
+	}
+	defenition.LockUntil = 0
+	defenition.LockOwner = ""
+	return repo.store.setDocument(id, defenition)
+}
+
+func (repo MemoryRepository) AppendGameEvents(id string, defenition AppendGameEventsDefenition) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return err
+	}
+
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	document, ok, err := repo.store.getDocument(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return mongo.ErrNoDocuments
+	}
+	if len(document.Events) != defenition.ExpectedEventCount {
+		return ErrEventCountMismatch
+	}
+
+	document.Events = append(document.Events, defenition.getEvents()...)
+	if defenition.Winner != "" {
+		document.Winner = defenition.Winner
+	}
+	if defenition.Draw {
+		document.Draw = true
+	}
+	if defenition.EndReason != "" {
+		document.EndReason = defenition.EndReason
+	}
+	document.TurnDeadline = defenition.TurnDeadline
+	document.EliminatedPlayers = defenition.EliminatedPlayers
+	if len(defenition.WinningPlayers) > 0 {
+		document.WinningPlayers = defenition.WinningPlayers
+	}
+	if defenition.Snapshot != nil {
+		document.Snapshot = defenition.Snapshot
+	}
+	return repo.store.setDocument(id, document)
+}
+
+func (repo MemoryRepository) GetPlayersGameStats(playerIds []string) ([]PlayerGameStats, error) {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	stats := []PlayerGameStats{}
+	for _, playerId := range playerIds {
+		stat := PlayerGameStats{
+			PlayerId: playerId,
+			Wins:     0,
+			Games:    0,
+			Draws:    0,
+			Losses:   0,
+		}
+
+		for _, id := range repo.store.ids {
+			document, _, err := repo.store.getDocument(id)
+			if err != nil {
+				return []PlayerGameStats{}, err
+			}
+			isOver := document.Winner != "" || document.Draw
+			if !isOver || document.ForkedFrom != "" || !containsPlayer(document.PlayerIds, playerId) {
+				continue
+			}
+			stat.Games += 1
+			if document.Draw {
+				stat.Draws += 1
+			} else if document.Winner == playerId || containsPlayer(document.WinningPlayers, playerId) {
+				stat.Wins += 1
+			} else {
+				stat.Losses += 1
+			}
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+func (repo MemoryRepository) GetWinStreak(playerId string) (WinStreak, error) {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	gameTimes := []GameTime{}
+	for _, id := range repo.store.ids {
+		document, _, err := repo.store.getDocument(id)
+		if err != nil {
+			return WinStreak{}, err
+		}
+		isWinner := document.Winner == playerId || containsPlayer(document.WinningPlayers, playerId)
+		if isWinner && document.ForkedFrom == "" {
+			gameTimes = append(gameTimes, GameTime{StartTime: document.StartTime})
+		}
+	}
+
+	sort.SliceStable(gameTimes, func(i, j int) bool {
+		return gameTimes[i].StartTime > gameTimes[j].StartTime
+	})
+
+	return calculateWinStreak(gameTimes, time.Now()), nil
+}
+
+func (repo MemoryRepository) GetExpiredTurnGames(now int64) ([]ExpiredTurnGame, error) {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	games := make([]ExpiredTurnGame, 0)
+	for _, id := range repo.store.ids {
+		document, _, err := repo.store.getDocument(id)
+		if err != nil {
+			return []ExpiredTurnGame{}, err
+		}
+		isOngoing := document.Winner == "" && !document.Draw && document.ForkedFrom == ""
+		if isOngoing && document.TurnDeadline > 0 && document.TurnDeadline <= now {
+			games = append(games, ExpiredTurnGame{
+				Id:         id,
+				EventCount: len(document.Events),
+			})
+		}
+	}
+	return games, nil
+}
+
+func containsPlayer(playerIds []string, playerId string) bool {
+	for _, id := range playerIds {
+		if id == playerId {
+			return true
+		}
+	}
+	return false
+}
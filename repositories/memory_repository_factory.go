@@ -0,0 +1,17 @@
+package repositories
+
+type MemoryRepositoryFactory struct {
+	repo MemoryRepository
+}
+
+func NewMemoryRepositoryFactory() MemoryRepositoryFactory {
+	return MemoryRepositoryFactory{
+		repo: NewMemoryRepository(),
+	}
+}
+
+func (factory MemoryRepositoryFactory) GetRepository() (Repository, func(), error) {
+	cleanUpFunc := func() {}
+
+	return factory.repo, cleanUpFunc, nil
+}
@@ -0,0 +1,7 @@
+package repositories
+
+import "testing"
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	testRepositoryConformance(t, NewMemoryRepositoryFactory())
+}
@@ -16,15 +16,47 @@ type MongoRepository struct {
 }
 
 type InserGameBoardDefenition struct {
-	PlayerIds   []string `bson:"player_ids"`
-	YMax        int      `bson:"y_max"`
-	XMax        int      `bson:"x_max"`
-	TargetScore int      `bson:"target_score"`
-	LockUntil   int      `bson:"lock_until"`
-	TimePerTurn int      `bson:"time_per_turn"`
-	StartTime   int64    `bson:"start_time"`
-	Winner      string
-	Events      []map[string]interface{}
+	PlayerIds         []string              `bson:"player_ids"`
+	YMax              int                   `bson:"y_max"`
+	XMax              int                   `bson:"x_max"`
+	TargetScore       int                   `bson:"target_score"`
+	LockUntil         int64                 `bson:"lock_until"`
+	LockOwner         string                `bson:"lock_owner"`
+	TimePerTurn       int                   `bson:"time_per_turn"`
+	PawnDurability    int                   `bson:"pawn_durability"`
+	ShufflesPerTurn   int                   `bson:"shuffles_per_turn"`
+	BlockerChance     int                   `bson:"blocker_chance"`
+	SplitterChance    int                   `bson:"splitter_chance"`
+	PortalChance      int                   `bson:"portal_chance"`
+	UndosPerTurn      int                   `bson:"undos_per_turn"`
+	MaxTimeouts       int                   `bson:"max_timeouts"`
+	TimeControl       TimeControlDefenition `bson:"time_control"`
+	StartTime         int64                 `bson:"start_time"`
+	TurnDeadline      int64                 `bson:"turn_deadline"`
+	VarianceVersion   int                   `bson:"variance_version"`
+	Seed              string                `bson:"seed"`
+	ForkedFrom        string                `bson:"forked_from"`
+	ForkEventIndex    int                   `bson:"fork_event_index"`
+	Draw              bool                  `bson:"draw"`
+	EndReason         string                `bson:"end_reason"`
+	EliminatedPlayers []string              `bson:"eliminated_players"`
+	WinningPlayers    []string              `bson:"winning_players"`
+	Teams             []TeamDefenition      `bson:"teams"`
+	Winner            string
+	Events            []map[string]interface{}
+	Snapshot          *GameSnapshot `bson:"snapshot,omitempty"`
+}
+
+type TeamDefenition struct {
+	Id        string   `bson:"id"`
+	PlayerIds []string `bson:"player_ids"`
+}
+
+// TimeControlDefenition is in milliseconds, the games without a bank use the time per turn
+type TimeControlDefenition struct {
+	Bank      int64 `bson:"bank"`
+	Increment int64 `bson:"increment"`
+	Delay     int64 `bson:"delay"`
 }
 
 func (repo MongoRepository) InsertGame(defenition InserGameBoardDefenition) (string, error) {
@@ -42,11 +74,34 @@ type GetGameBoardDefenitionResult struct {
 	XMax        int      `bson:"x_max"`
 	TargetScore int      `bson:"target_score"`
 	TimePerTurn int      `bson:"time_per_turn"`
-	StartTime   int64    `bson:"start_time"`
-	Events      []map[string]interface{}
+	// the rules that are nil were not stored with the older games
+	PawnDurability  *int                  `bson:"pawn_durability"`
+	ShufflesPerTurn *int                  `bson:"shuffles_per_turn"`
+	BlockerChance   int                   `bson:"blocker_chance"`
+	SplitterChance  int                   `bson:"splitter_chance"`
+	PortalChance    int                   `bson:"portal_chance"`
+	UndosPerTurn    int                   `bson:"undos_per_turn"`
+	MaxTimeouts     int                   `bson:"max_timeouts"`
+	TimeControl     TimeControlDefenition `bson:"time_control"`
+	StartTime       int64                 `bson:"start_time"`
+	VarianceVersion int                   `bson:"variance_version"`
+	Seed            string                `bson:"seed"`
+	ForkedFrom      string                `bson:"forked_from"`
+	ForkEventIndex  int                   `bson:"fork_event_index"`
+	Teams           []TeamDefenition      `bson:"teams"`
+	Events          []map[string]interface{}
+	Snapshot        *GameSnapshot `bson:"snapshot"`
 }
 
-func (repo MongoRepository) GetGameAndLock(id string) (GetGameBoardDefenitionResult, error) {
+// notForked matches the games that were played for real, forks are sandboxes
+// and must never count for the stats or be picked up as a player's ongoing game
+func notForked() bson.E {
+	return bson.E{Key: "forked_from", Value: bson.D{
+		{Key: "$in", Value: bson.A{nil, ""}},
+	}}
+}
+
+func (repo MongoRepository) GetGameAndLock(id string, lockToken string) (GetGameBoardDefenitionResult, error) {
 	var result GetGameBoardDefenitionResult
 
 	objectId, err := primitive.ObjectIDFromHex(id)
@@ -54,13 +109,12 @@ func (repo MongoRepository) GetGameAndLock(id string) (GetGameBoardDefenitionRes
 		return GetGameBoardDefenitionResult{}, err
 	}
 
-	now := time.Now().Unix()
+	now := time.Now().UnixMilli()
 
-	// if something is locked for more than 5 seconds
-	// it means that something went wrong and it will no longer be locked
 	update := bson.D{
 		{Key: "$set", Value: bson.D{
-			{Key: "lock_until", Value: now + 5},
+			{Key: "lock_until", Value: now + gameLockLease.Milliseconds()},
+			{Key: "lock_owner", Value: lockToken},
 		}},
 	}
 
@@ -70,7 +124,18 @@ func (repo MongoRepository) GetGameAndLock(id string) (GetGameBoardDefenitionRes
 			{Key: "$lte", Value: now},
 		}},
 	}
-	err = repo.client.Database("game_management").Collection("games").FindOneAndUpdate(repo.ctx, filter, update).Decode(&result)
+	collection := repo.client.Database("game_management").Collection("games")
+	err = collection.FindOneAndUpdate(repo.ctx, filter, update).Decode(&result)
+
+	if err == mongo.ErrNoDocuments {
+		count, countErr := collection.CountDocuments(repo.ctx, bson.D{{Key: "_id", Value: objectId}})
+		if countErr != nil {
+			return GetGameBoardDefenitionResult{}, countErr
+		}
+		if count > 0 {
+			return GetGameBoardDefenitionResult{}, ErrGameLocked
+		}
+	}
 
 	if err != nil {
 		return GetGameBoardDefenitionResult{}, err
@@ -78,17 +143,21 @@ func (repo MongoRepository) GetGameAndLock(id string) (GetGameBoardDefenitionRes
 	return result, nil
 }
 
-func (repo MongoRepository) UnlockGame(id string) error {
+func (repo MongoRepository) UnlockGame(id string, lockToken string) error {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	filter := bson.D{{Key: "_id", Value: objectId}}
+	filter := bson.D{
+		{Key: "_id", Value: objectId},
+		{Key: "lock_owner", Value: lockToken},
+	}
 
 	update := bson.D{
 		{Key: "$set", Value: bson.D{
 			{Key: "lock_until", Value: 0},
+			{Key: "lock_owner", Value: ""},
 		}},
 	}
 
@@ -120,6 +189,13 @@ func (repo MongoRepository) GetOngoingPlayerGame(playerId string) (GetGameBoardD
 	filter := bson.D{
 		{Key: "player_ids", Value: playerId},
 		{Key: "winner", Value: ""},
+		{Key: "draw", Value: bson.D{
+			{Key: "$ne", Value: true},
+		}},
+		{Key: "eliminated_players", Value: bson.D{
+			{Key: "$ne", Value: playerId},
+		}},
+		notForked(),
 	}
 	err := repo.client.Database("game_management").Collection("games").FindOne(repo.ctx, filter).Decode(&result)
 
@@ -138,16 +214,108 @@ func (repo MongoRepository) ReplaceGame(id string, defenition InserGameBoardDefe
 	}
 	filter := bson.D{{Key: "_id", Value: objectId}}
 	defenition.LockUntil = 0
+	defenition.LockOwner = ""
 
 	_, err = repo.client.Database("game_management").Collection("games").ReplaceOne(repo.ctx, filter, defenition)
 
 	return err
 }
 
+// AppendGameEventsDefenition holds the events that were added after the game was read.
+// ExpectedEventCount is the number of events the game had at that time, so a writer
+// that is working on an outdated game gets rejected instead of overwriting the history.
+// EndReason is why the game ended, it is only set once the game is over.
+// TurnDeadline is when the current turn runs out, 0 once the game is over.
+// EliminatedPlayers are out of the game while the others keep playing.
+// WinningPlayers are all the players of the winner, the members of a team share the win.
+// Snapshot replaces the stored snapshot when it is set
+type AppendGameEventsDefenition struct {
+	ExpectedEventCount int
+	Events             []map[string]interface{}
+	Winner             string
+	Draw               bool
+	EndReason          string
+	TurnDeadline       int64
+	EliminatedPlayers  []string
+	WinningPlayers     []string
+	Snapshot           *GameSnapshot
+}
+
+// getEvents treats missing events as no events, $each does not accept null
+func (defenition AppendGameEventsDefenition) getEvents() []map[string]interface{} {
+	if defenition.Events == nil {
+		return []map[string]interface{}{}
+	}
+	return defenition.Events
+}
+
+func (repo MongoRepository) AppendGameEvents(id string, defenition AppendGameEventsDefenition) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: objectId},
+		{Key: "events", Value: bson.D{
+			{Key: "$size", Value: defenition.ExpectedEventCount},
+		}},
+	}
+
+	update := bson.D{
+		{Key: "$push", Value: bson.D{
+			{Key: "events", Value: bson.D{
+				{Key: "$each", Value: defenition.getEvents()},
+			}},
+		}},
+	}
+	set := bson.D{
+		{Key: "turn_deadline", Value: defenition.TurnDeadline},
+		{Key: "eliminated_players", Value: defenition.EliminatedPlayers},
+	}
+	if defenition.Winner != "" {
+		set = append(set, bson.E{Key: "winner", Value: defenition.Winner})
+	}
+	if len(defenition.WinningPlayers) > 0 {
+		set = append(set, bson.E{Key: "winning_players", Value: defenition.WinningPlayers})
+	}
+	if defenition.Draw {
+		set = append(set, bson.E{Key: "draw", Value: true})
+	}
+	if defenition.EndReason != "" {
+		set = append(set, bson.E{Key: "end_reason", Value: defenition.EndReason})
+	}
+	if defenition.Snapshot != nil {
+		set = append(set, bson.E{Key: "snapshot", Value: defenition.Snapshot})
+	}
+	update = append(update, bson.E{Key: "$set", Value: set})
+
+	collection := repo.client.Database("game_management").Collection("games")
+	result, err := collection.UpdateOne(repo.ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := collection.CountDocuments(repo.ctx, bson.D{{Key: "_id", Value: objectId}})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return ErrEventCountMismatch
+	}
+
+	return nil
+}
+
 type PlayerGameStats struct {
 	PlayerId string
 	Games    int
 	Wins     int
+	Draws    int
+	Losses   int
 }
 
 func (repo MongoRepository) GetPlayersGameStats(playerIds []string) ([]PlayerGameStats, error) {
@@ -168,10 +336,14 @@ func getPlayerGameStats(repo MongoRepository, playerId string) (PlayerGameStats,
 
 	match := bson.D{
 		{Key: "player_ids", Value: playerId},
-		{Key: "winner", Value: bson.D{
-			{Key: "$ne", Value: ""},
-			{Key: "$exists", Value: 1},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "winner", Value: bson.D{
+				{Key: "$ne", Value: ""},
+				{Key: "$exists", Value: 1},
+			}}},
+			bson.D{{Key: "draw", Value: true}},
 		}},
+		notForked(),
 	}
 
 	group := bson.D{
@@ -185,7 +357,12 @@ func getPlayerGameStats(repo MongoRepository, playerId string) (PlayerGameStats,
 					{Key: "branches", Value: bson.A{
 						bson.D{
 							{Key: "case", Value: bson.D{
-								{Key: "$eq", Value: bson.A{"$winner", playerId}},
+								{Key: "$or", Value: bson.A{
+									bson.D{{Key: "$eq", Value: bson.A{"$winner", playerId}}},
+									bson.D{{Key: "$in", Value: bson.A{playerId, bson.D{
+										{Key: "$ifNull", Value: bson.A{"$winning_players", bson.A{}}},
+									}}}},
+								}},
 							}},
 							{Key: "then", Value: 1},
 						},
@@ -194,6 +371,13 @@ func getPlayerGameStats(repo MongoRepository, playerId string) (PlayerGameStats,
 				}},
 			}},
 		}},
+		{Key: "draws", Value: bson.D{
+			{Key: "$sum", Value: bson.D{
+				{Key: "$cond", Value: bson.A{
+					bson.D{{Key: "$eq", Value: bson.A{"$draw", true}}}, 1, 0,
+				}},
+			}},
+		}},
 	}
 
 	cursor, err := repo.client.Database("game_management").Collection("games").Aggregate(repo.ctx, mongo.Pipeline{
@@ -217,11 +401,15 @@ func getPlayerGameStats(repo MongoRepository, playerId string) (PlayerGameStats,
 		PlayerId: playerId,
 		Wins:     0,
 		Games:    0,
+		Draws:    0,
+		Losses:   0,
 	}
 
 	if len(results) > 0 {
 		stats.Wins = int(results[0]["wins"].(int32))
 		stats.Games = int(results[0]["totalGames"].(int32))
+		stats.Draws = int(results[0]["draws"].(int32))
+		stats.Losses = stats.Games - stats.Wins - stats.Draws
 	}
 
 	return stats, nil
@@ -238,8 +426,12 @@ func (repo MongoRepository) GetWinStreak(playerId string) (WinStreak, error) {
 	if err != nil {
 		return WinStreak{}, err
 	}
+	return calculateWinStreak(gameTimes, time.Now()), nil
+}
+
+// gameTimes must be sorted from the most recent to the oldest
+func calculateWinStreak(gameTimes []GameTime, now time.Time) WinStreak {
 	hasWonToday := false
-	now := time.Now()
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	dayCheck := today
 	totalDayStreak := 0
@@ -268,7 +460,7 @@ func (repo MongoRepository) GetWinStreak(playerId string) (WinStreak, error) {
 		HasWonToday: hasWonToday,
 		WinStreak:   totalDayStreak,
 		NextDay:     time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()).UnixMilli(),
-	}, nil
+	}
 }
 
 type GameTime struct {
@@ -277,7 +469,11 @@ type GameTime struct {
 
 func getWonGamesStartTimes(repo MongoRepository, playerId string) ([]GameTime, error) {
 	filter := bson.D{
-		{Key: "winner", Value: playerId},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "winner", Value: playerId}},
+			bson.D{{Key: "winning_players", Value: playerId}},
+		}},
+		notForked(),
 	}
 	opt := options.Find()
 	opt.Projection = bson.D{
@@ -304,3 +500,57 @@ func getWonGamesStartTimes(repo MongoRepository, playerId string) ([]GameTime, e
 
 	return results, nil
 }
+
+// ExpiredTurnGame is an ongoing game whose current turn ran out,
+// EventCount is the number of events it had when it was found
+type ExpiredTurnGame struct {
+	Id         string
+	EventCount int
+}
+
+// GetExpiredTurnGames finds the ongoing games whose turn deadline is before now,
+// the games that were stored before the deadline existed and the forks are never returned
+func (repo MongoRepository) GetExpiredTurnGames(now int64) ([]ExpiredTurnGame, error) {
+	match := bson.D{
+		{Key: "turn_deadline", Value: bson.D{
+			{Key: "$gt", Value: 0},
+			{Key: "$lte", Value: now},
+		}},
+		{Key: "winner", Value: ""},
+		{Key: "draw", Value: bson.D{
+			{Key: "$ne", Value: true},
+		}},
+		notForked(),
+	}
+
+	project := bson.D{
+		{Key: "event_count", Value: bson.D{
+			{Key: "$size", Value: "$events"},
+		}},
+	}
+
+	cursor, err := repo.client.Database("game_management").Collection("games").Aggregate(repo.ctx, mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$project", Value: project}},
+	})
+	if err != nil {
+		return []ExpiredTurnGame{}, err
+	}
+
+	var results []struct {
+		Id         primitive.ObjectID `bson:"_id"`
+		EventCount int                `bson:"event_count"`
+	}
+	if err = cursor.All(repo.ctx, &results); err != nil {
+		return []ExpiredTurnGame{}, err
+	}
+
+	games := make([]ExpiredTurnGame, 0)
+	for _, result := range results {
+		games = append(games, ExpiredTurnGame{
+			Id:         result.Id.Hex(),
+			EventCount: result.EventCount,
+		})
+	}
+	return games, nil
+}
@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMongoRepositoryConformance(t *testing.T) {
+	if os.Getenv("DB_URL") == "" {
+		t.Skip("DB_URL is not set")
+	}
+	testRepositoryConformance(t, getMongoRepositoryFactory())
+}
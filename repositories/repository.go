@@ -1,5 +1,12 @@
 package repositories
 
+import (
+	"errors"
+	"os"
+)
+
+var ErrEventCountMismatch = errors.New("the game has changed since it was read")
+
 type RepositoryFactory interface {
 	GetRepository() (Repository, func(), error)
 }
@@ -7,14 +14,20 @@ type RepositoryFactory interface {
 type Repository interface {
 	InsertGame(defenition InserGameBoardDefenition) (string, error)
 	ReplaceGame(objectId string, defenition InserGameBoardDefenition) error
+	AppendGameEvents(id string, defenition AppendGameEventsDefenition) error
 	GetGame(id string) (GetGameBoardDefenitionResult, error)
-	UnlockGame(id string) error
-	GetGameAndLock(id string) (GetGameBoardDefenitionResult, error)
+	UnlockGame(id string, lockToken string) error
+	GetGameAndLock(id string, lockToken string) (GetGameBoardDefenitionResult, error)
 	GetPlayersGameStats(playerIds []string) ([]PlayerGameStats, error)
 	GetOngoingPlayerGame(playerId string) (GetGameBoardDefenitionResult, error)
 	GetWinStreak(playerId string) (WinStreak, error)
+	GetExpiredTurnGames(now int64) ([]ExpiredTurnGame, error)
 }
 
+// the DB_DRIVER env var picks the backend, mongo is used when it is not set
 func GetRepositoryFactory() RepositoryFactory {
+	if os.Getenv("DB_DRIVER") == "memory" {
+		return NewMemoryRepositoryFactory()
+	}
 	return getMongoRepositoryFactory()
 }
@@ -0,0 +1,530 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// testRepositoryConformance checks the contract every Repository backend has to honour.
+// The player ids are unique per run so that it can also be pointed at a shared database
+func testRepositoryConformance(t *testing.T, factory RepositoryFactory) {
+	getRepository := func(t *testing.T) Repository {
+		repo, cleanup, err := factory.GetRepository()
+		if err != nil {
+			t.Fatalf("Failed to get repository: %v", err)
+		}
+		t.Cleanup(cleanup)
+		return repo
+	}
+
+	t.Run("InsertAndGetGame", func(t *testing.T) {
+		repo := getRepository(t)
+		playerIds := []string{newTestPlayerId("red"), newTestPlayerId("blue")}
+		id := insertTestGame(t, repo, playerIds, "", time.Now().UnixMilli())
+
+		game, err := repo.GetGame(id)
+		if err != nil {
+			t.Fatalf("Failed to get game: %v", err)
+		}
+		if game.Id != id || len(game.PlayerIds) != 2 || game.PlayerIds[0] != playerIds[0] || game.PlayerIds[1] != playerIds[1] {
+			t.Errorf("Got the wrong game %+v", game)
+		}
+		if game.XMax != 2 || game.YMax != 2 || game.TargetScore != 6 || game.TimePerTurn != 45000 {
+			t.Errorf("Game settings were not stored %+v", game)
+		}
+		if len(game.Events) != 2 {
+			t.Fatalf("Expected 2 events, got %d", len(game.Events))
+		}
+		if game.Events[0]["name"] != "create_pawn" || game.Events[0]["position_x"] != int32(1) {
+			t.Errorf("Event was not decoded as stored %v", game.Events[0])
+		}
+		if game.Events[1]["end_time"] != int64(1650000000000) {
+			t.Errorf("Event was not decoded as stored %v", game.Events[1])
+		}
+	})
+
+	t.Run("GetMissingGame", func(t *testing.T) {
+		repo := getRepository(t)
+		if _, err := repo.GetGame(primitive.NewObjectID().Hex()); err == nil {
+			t.Errorf("Got a game that does not exist")
+		}
+		if _, err := repo.GetGame("not an id"); err == nil {
+			t.Errorf("Got a game with an invalid id")
+		}
+		if _, err := repo.GetGameAndLock(primitive.NewObjectID().Hex(), "owner"); err == nil {
+			t.Errorf("Locked a game that does not exist")
+		}
+	})
+
+	t.Run("LockContention", func(t *testing.T) {
+		repo := getRepository(t)
+		id := insertTestGame(t, repo, []string{newTestPlayerId("red"), newTestPlayerId("blue")}, "", time.Now().UnixMilli())
+
+		game, err := repo.GetGameAndLock(id, "owner")
+		if err != nil || game.Id != id {
+			t.Fatalf("Failed to lock game: %v", err)
+		}
+		if _, err := repo.GetGameAndLock(id, "intruder"); err != ErrGameLocked {
+			t.Errorf("Expected the game to be locked, got %v", err)
+		}
+		if _, err := repo.GetGame(id); err != nil {
+			t.Errorf("A lock should not prevent reading the game: %v", err)
+		}
+
+		if err := repo.UnlockGame(id, "intruder"); err != nil {
+			t.Fatalf("Failed to unlock game: %v", err)
+		}
+		if _, err := repo.GetGameAndLock(id, "intruder"); err != ErrGameLocked {
+			t.Errorf("Only the owner should be able to release the lock, got %v", err)
+		}
+
+		if err := repo.UnlockGame(id, "owner"); err != nil {
+			t.Fatalf("Failed to unlock game: %v", err)
+		}
+		if _, err := repo.GetGameAndLock(id, "next owner"); err != nil {
+			t.Errorf("Failed to lock an unlocked game: %v", err)
+		}
+	})
+
+	t.Run("LockExpiry", func(t *testing.T) {
+		repo := getRepository(t)
+		id := insertTestGame(t, repo, []string{newTestPlayerId("red"), newTestPlayerId("blue")}, "", time.Now().UnixMilli())
+
+		lease := gameLockLease
+		gameLockLease = 200 * time.Millisecond
+		defer func() { gameLockLease = lease }()
+
+		if _, err := repo.GetGameAndLock(id, "owner"); err != nil {
+			t.Fatalf("Failed to lock game: %v", err)
+		}
+		time.Sleep(300 * time.Millisecond)
+		if _, err := repo.GetGameAndLock(id, "next owner"); err != nil {
+			t.Errorf("Lock did not expire: %v", err)
+		}
+
+		// the expired owner must not release the lock of the next owner
+		if err := repo.UnlockGame(id, "owner"); err != nil {
+			t.Fatalf("Failed to unlock game: %v", err)
+		}
+		if _, err := repo.GetGameAndLock(id, "owner"); err != ErrGameLocked {
+			t.Errorf("Expected the game to be locked, got %v", err)
+		}
+	})
+
+	t.Run("ReplaceGameClearsLock", func(t *testing.T) {
+		repo := getRepository(t)
+		playerIds := []string{newTestPlayerId("red"), newTestPlayerId("blue")}
+		id := insertTestGame(t, repo, playerIds, "", time.Now().UnixMilli())
+
+		if _, err := repo.GetGameAndLock(id, "owner"); err != nil {
+			t.Fatalf("Failed to lock game: %v", err)
+		}
+
+		defenition := newTestDefenition(playerIds, playerIds[1], time.Now().UnixMilli())
+		defenition.Events = append(defenition.Events, map[string]interface{}{"name": "fire_deflector"})
+		defenition.LockUntil = time.Now().UnixMilli() + 100000
+		defenition.LockOwner = "owner"
+		if err := repo.ReplaceGame(id, defenition); err != nil {
+			t.Fatalf("Failed to replace game: %v", err)
+		}
+
+		game, err := repo.GetGameAndLock(id, "next owner")
+		if err != nil {
+			t.Fatalf("Replacing the game did not clear the lock: %v", err)
+		}
+		if len(game.Events) != 3 {
+			t.Errorf("Expected the replaced events, got %d events", len(game.Events))
+		}
+	})
+
+	t.Run("AppendGameEvents", func(t *testing.T) {
+		repo := getRepository(t)
+		playerIds := []string{newTestPlayerId("red"), newTestPlayerId("blue")}
+		id := insertTestGame(t, repo, playerIds, "", time.Now().UnixMilli())
+
+		err := repo.AppendGameEvents(id, AppendGameEventsDefenition{
+			ExpectedEventCount: 2,
+			Events: []map[string]interface{}{
+				{"name": "fire_deflector"},
+				{"name": "game_win", "player_owner": playerIds[1]},
+			},
+			Winner: playerIds[1],
+		})
+		if err != nil {
+			t.Fatalf("Failed to append events: %v", err)
+		}
+
+		game, err := repo.GetGame(id)
+		if err != nil {
+			t.Fatalf("Failed to get game: %v", err)
+		}
+		if len(game.Events) != 4 || game.Events[0]["name"] != "create_pawn" || game.Events[3]["name"] != "game_win" {
+			t.Errorf("Events were not appended in order %v", game.Events)
+		}
+		if _, err := repo.GetOngoingPlayerGame(playerIds[0]); err == nil {
+			t.Errorf("Appending the winner did not end the game")
+		}
+
+		err = repo.AppendGameEvents(id, AppendGameEventsDefenition{
+			ExpectedEventCount: 2,
+			Events:             []map[string]interface{}{{"name": "fire_deflector"}},
+		})
+		if err != ErrEventCountMismatch {
+			t.Errorf("Expected a stale writer to be rejected, got %v", err)
+		}
+
+		game, err = repo.GetGame(id)
+		if err != nil || len(game.Events) != 4 {
+			t.Errorf("A rejected append changed the events %v", game.Events)
+		}
+
+		err = repo.AppendGameEvents(primitive.NewObjectID().Hex(), AppendGameEventsDefenition{
+			ExpectedEventCount: 0,
+			Events:             []map[string]interface{}{{"name": "fire_deflector"}},
+		})
+		if err == nil || err == ErrEventCountMismatch {
+			t.Errorf("Expected a missing game error, got %v", err)
+		}
+	})
+
+	t.Run("AppendGameEventsWithSnapshot", func(t *testing.T) {
+		repo := getRepository(t)
+		playerIds := []string{newTestPlayerId("red"), newTestPlayerId("blue")}
+		id := insertTestGame(t, repo, playerIds, "", time.Now().UnixMilli())
+
+		game, err := repo.GetGame(id)
+		if err != nil || game.Snapshot != nil {
+			t.Fatalf("A new game should not have a snapshot %v %v", game.Snapshot, err)
+		}
+
+		snapshot := GameSnapshot{
+			Version:    1,
+			EventCount: 3,
+			Turn:       1,
+			Pawns: []SnapshotPawn{
+				{X: 1, Y: 2, Name: "slash", TurnPlaced: 0, Durability: 4, PlayerOwner: playerIds[0]},
+			},
+			ScoreBoard:          map[string]int{playerIds[0]: 0, playerIds[1]: 1},
+			PawnVariants:        map[string][]string{playerIds[0]: {"slash", "backslash"}, playerIds[1]: {"slash"}},
+			AvailableShuffles:   map[string]int{playerIds[0]: 1, playerIds[1]: 1},
+			PlayersInMatchPoint: map[string]bool{playerIds[0]: false, playerIds[1]: true},
+			GameInProgress:      true,
+			LastTurnEndTime:     1650000000000,
+		}
+		err = repo.AppendGameEvents(id, AppendGameEventsDefenition{
+			ExpectedEventCount: 2,
+			Events:             []map[string]interface{}{{"name": "fire_deflector"}},
+			Snapshot:           &snapshot,
+		})
+		if err != nil {
+			t.Fatalf("Failed to append events: %v", err)
+		}
+
+		err = repo.AppendGameEvents(id, AppendGameEventsDefenition{
+			ExpectedEventCount: 3,
+			Events:             []map[string]interface{}{{"name": "fire_deflector"}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to append events: %v", err)
+		}
+
+		game, err = repo.GetGame(id)
+		if err != nil || game.Snapshot == nil {
+			t.Fatalf("Snapshot was not stored %v", err)
+		}
+		if !reflect.DeepEqual(*game.Snapshot, snapshot) {
+			t.Errorf("Expected snapshot %+v, got %+v", snapshot, *game.Snapshot)
+		}
+	})
+
+	t.Run("GetOngoingPlayerGame", func(t *testing.T) {
+		repo := getRepository(t)
+		red, blue := newTestPlayerId("red"), newTestPlayerId("blue")
+		insertTestGame(t, repo, []string{red, blue}, red, time.Now().UnixMilli())
+		drawId := insertTestGame(t, repo, []string{red, blue}, "", time.Now().UnixMilli())
+		err := repo.AppendGameEvents(drawId, AppendGameEventsDefenition{ExpectedEventCount: 2, Events: []map[string]interface{}{}, Draw: true})
+		if err != nil {
+			t.Fatalf("Failed to end the game in a draw: %v", err)
+		}
+		ongoingId := insertTestGame(t, repo, []string{red, blue}, "", time.Now().UnixMilli())
+
+		for _, playerId := range []string{red, blue} {
+			game, err := repo.GetOngoingPlayerGame(playerId)
+			if err != nil || game.Id != ongoingId {
+				t.Errorf("Expected ongoing game %s, got %s (%v)", ongoingId, game.Id, err)
+			}
+		}
+
+		if _, err := repo.GetOngoingPlayerGame(newTestPlayerId("green")); err == nil {
+			t.Errorf("Got an ongoing game for a player without games")
+		}
+	})
+
+	t.Run("WinningPlayersShareTheWin", func(t *testing.T) {
+		repo := getRepository(t)
+		red, blue, green, yellow := newTestPlayerId("red"), newTestPlayerId("blue"), newTestPlayerId("green"), newTestPlayerId("yellow")
+		gameId := insertTestGame(t, repo, []string{red, green, blue, yellow}, "", time.Now().UnixMilli())
+		err := repo.AppendGameEvents(gameId, AppendGameEventsDefenition{
+			ExpectedEventCount: 2,
+			Events:             []map[string]interface{}{},
+			Winner:             "team:1",
+			WinningPlayers:     []string{red, blue},
+		})
+		if err != nil {
+			t.Fatalf("Failed to end the game: %v", err)
+		}
+
+		stats, err := repo.GetPlayersGameStats([]string{red, blue, green, yellow})
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		expected := []PlayerGameStats{
+			{PlayerId: red, Games: 1, Wins: 1, Draws: 0, Losses: 0},
+			{PlayerId: blue, Games: 1, Wins: 1, Draws: 0, Losses: 0},
+			{PlayerId: green, Games: 1, Wins: 0, Draws: 0, Losses: 1},
+			{PlayerId: yellow, Games: 1, Wins: 0, Draws: 0, Losses: 1},
+		}
+		if !reflect.DeepEqual(stats, expected) {
+			t.Errorf("Expected stats %v, got %v", expected, stats)
+		}
+
+		for _, playerId := range []string{red, blue} {
+			streak, err := repo.GetWinStreak(playerId)
+			if err != nil || !streak.HasWonToday {
+				t.Errorf("The team win is not in the streak of %s (%v)", playerId, err)
+			}
+		}
+	})
+
+	t.Run("EliminatedPlayersHaveNoOngoingGame", func(t *testing.T) {
+		repo := getRepository(t)
+		red, blue, green := newTestPlayerId("red"), newTestPlayerId("blue"), newTestPlayerId("green")
+		gameId := insertTestGame(t, repo, []string{red, blue, green}, "", time.Now().UnixMilli())
+		err := repo.AppendGameEvents(gameId, AppendGameEventsDefenition{ExpectedEventCount: 2, Events: []map[string]interface{}{}, EliminatedPlayers: []string{red}})
+		if err != nil {
+			t.Fatalf("Failed to eliminate the player: %v", err)
+		}
+
+		if _, err := repo.GetOngoingPlayerGame(red); err == nil {
+			t.Errorf("Got an ongoing game for an eliminated player")
+		}
+		for _, playerId := range []string{blue, green} {
+			game, err := repo.GetOngoingPlayerGame(playerId)
+			if err != nil || game.Id != gameId {
+				t.Errorf("Expected ongoing game %s, got %s (%v)", gameId, game.Id, err)
+			}
+		}
+	})
+
+	t.Run("GetPlayersGameStats", func(t *testing.T) {
+		repo := getRepository(t)
+		red, blue, green, yellow := newTestPlayerId("red"), newTestPlayerId("blue"), newTestPlayerId("green"), newTestPlayerId("yellow")
+		now := time.Now().UnixMilli()
+		insertTestGame(t, repo, []string{red, blue}, red, now)
+		insertTestGame(t, repo, []string{red, blue}, red, now)
+		insertTestGame(t, repo, []string{blue, red}, blue, now)
+		insertTestGame(t, repo, []string{red, green}, green, now)
+		insertTestGame(t, repo, []string{red, blue}, "", now)
+		drawId := insertTestGame(t, repo, []string{blue, red}, "", now)
+		err := repo.AppendGameEvents(drawId, AppendGameEventsDefenition{ExpectedEventCount: 2, Events: []map[string]interface{}{}, Draw: true})
+		if err != nil {
+			t.Fatalf("Failed to end the game in a draw: %v", err)
+		}
+
+		stats, err := repo.GetPlayersGameStats([]string{red, blue, green, yellow})
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		if len(stats) != 4 {
+			t.Fatalf("Expected 4 stats, got %d", len(stats))
+		}
+
+		expected := []PlayerGameStats{
+			{PlayerId: red, Games: 5, Wins: 2, Draws: 1, Losses: 2},
+			{PlayerId: blue, Games: 4, Wins: 1, Draws: 1, Losses: 2},
+			{PlayerId: green, Games: 1, Wins: 1, Draws: 0, Losses: 0},
+			{PlayerId: yellow, Games: 0, Wins: 0, Draws: 0, Losses: 0},
+		}
+		for i := range expected {
+			if stats[i] != expected[i] {
+				t.Errorf("Expected %+v, got %+v", expected[i], stats[i])
+			}
+		}
+	})
+
+	t.Run("GetWinStreak", func(t *testing.T) {
+		repo := getRepository(t)
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		yesterday := today.AddDate(0, 0, -1)
+		tomorrow := today.AddDate(0, 0, 1)
+
+		streaker, opponent := newTestPlayerId("streaker"), newTestPlayerId("opponent")
+		// won at the very start of today and at the very end of yesterday
+		insertTestGame(t, repo, []string{streaker, opponent}, streaker, today.Add(time.Millisecond).UnixMilli())
+		insertTestGame(t, repo, []string{streaker, opponent}, streaker, today.Add(-time.Second).UnixMilli())
+		insertTestGame(t, repo, []string{streaker, opponent}, streaker, yesterday.Add(-12*time.Hour).UnixMilli())
+		// a lost game does not break the streak, a missing day does
+		insertTestGame(t, repo, []string{streaker, opponent}, opponent, yesterday.Add(-30*time.Hour).UnixMilli())
+		insertTestGame(t, repo, []string{streaker, opponent}, streaker, yesterday.Add(-60*time.Hour).UnixMilli())
+
+		streak, err := repo.GetWinStreak(streaker)
+		if err != nil {
+			t.Fatalf("Failed to get win streak: %v", err)
+		}
+		if !streak.HasWonToday || streak.WinStreak != 3 || streak.NextDay != tomorrow.UnixMilli() {
+			t.Errorf("Wrong win streak %+v", streak)
+		}
+
+		streak, err = repo.GetWinStreak(opponent)
+		if err != nil {
+			t.Fatalf("Failed to get win streak: %v", err)
+		}
+		if streak.HasWonToday || streak.WinStreak != 0 {
+			t.Errorf("Wrong win streak %+v", streak)
+		}
+
+		yesterdayWinner := newTestPlayerId("yesterday")
+		insertTestGame(t, repo, []string{yesterdayWinner, opponent}, yesterdayWinner, yesterday.UnixMilli())
+		streak, err = repo.GetWinStreak(yesterdayWinner)
+		if err != nil {
+			t.Fatalf("Failed to get win streak: %v", err)
+		}
+		if streak.HasWonToday || streak.WinStreak != 1 {
+			t.Errorf("Wrong win streak %+v", streak)
+		}
+	})
+	t.Run("ForksAreExcluded", func(t *testing.T) {
+		repo := getRepository(t)
+		red, blue := newTestPlayerId("red"), newTestPlayerId("blue")
+		now := time.Now().UnixMilli()
+		gameId := insertTestGame(t, repo, []string{red, blue}, red, now)
+
+		for _, winner := range []string{red, ""} {
+			fork := newTestDefenition([]string{red, blue}, winner, now)
+			fork.ForkedFrom = gameId
+			fork.ForkEventIndex = 1
+			if _, err := repo.InsertGame(fork); err != nil {
+				t.Fatalf("Failed to insert fork: %v", err)
+			}
+		}
+
+		stats, err := repo.GetPlayersGameStats([]string{red})
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		if stats[0].Games != 1 || stats[0].Wins != 1 {
+			t.Errorf("Forks were counted in %+v", stats[0])
+		}
+
+		streak, err := repo.GetWinStreak(red)
+		if err != nil {
+			t.Fatalf("Failed to get win streak: %v", err)
+		}
+		if streak.WinStreak != 1 {
+			t.Errorf("Forks were counted in %+v", streak)
+		}
+
+		if _, err := repo.GetOngoingPlayerGame(red); err != mongo.ErrNoDocuments {
+			t.Errorf("A fork was returned as the ongoing game: %v", err)
+		}
+	})
+
+	t.Run("GetExpiredTurnGames", func(t *testing.T) {
+		repo := getRepository(t)
+		red, blue := newTestPlayerId("red"), newTestPlayerId("blue")
+		now := time.Now().UnixMilli()
+
+		insertWithDeadline := func(winner string, turnDeadline int64, forkedFrom string) string {
+			defenition := newTestDefenition([]string{red, blue}, winner, now)
+			defenition.TurnDeadline = turnDeadline
+			defenition.ForkedFrom = forkedFrom
+			id, err := repo.InsertGame(defenition)
+			if err != nil {
+				t.Fatalf("Failed to insert game: %v", err)
+			}
+			return id
+		}
+
+		expiredId := insertWithDeadline("", now-1000, "")
+		appendedId := insertWithDeadline("", now+60*1000, "")
+		notExpired := []string{
+			insertWithDeadline("", now+60*1000, ""),
+			insertWithDeadline("", 0, ""),
+			insertWithDeadline(red, now-1000, ""),
+			insertWithDeadline("", now-1000, expiredId),
+		}
+
+		// the deadline moves with every append
+		err := repo.AppendGameEvents(appendedId, AppendGameEventsDefenition{
+			ExpectedEventCount: 2,
+			Events:             []map[string]interface{}{{"name": "fire_deflector"}},
+			TurnDeadline:       now - 500,
+		})
+		if err != nil {
+			t.Fatalf("Failed to append events: %v", err)
+		}
+
+		games, err := repo.GetExpiredTurnGames(now)
+		if err != nil {
+			t.Fatalf("Failed to get expired games: %v", err)
+		}
+		found := map[string]int{}
+		for _, game := range games {
+			found[game.Id] = game.EventCount
+		}
+
+		if eventCount, ok := found[expiredId]; !ok || eventCount != 2 {
+			t.Errorf("Expected the expired game with 2 events, got %v %d", ok, eventCount)
+		}
+		if eventCount, ok := found[appendedId]; !ok || eventCount != 3 {
+			t.Errorf("Expected the appended game with 3 events, got %v %d", ok, eventCount)
+		}
+		for _, id := range notExpired {
+			if _, ok := found[id]; ok {
+				t.Errorf("Game %s should not have expired", id)
+			}
+		}
+	})
+}
+
+func newTestPlayerId(name string) string {
+	return name + "-" + primitive.NewObjectID().Hex()
+}
+
+func newTestDefenition(playerIds []string, winner string, startTime int64) InserGameBoardDefenition {
+	return InserGameBoardDefenition{
+		PlayerIds:   playerIds,
+		YMax:        2,
+		XMax:        2,
+		TargetScore: 6,
+		TimePerTurn: 45 * 1000,
+		StartTime:   startTime,
+		Winner:      winner,
+		Events: []map[string]interface{}{
+			{
+				"name":         "create_pawn",
+				"position_x":   1,
+				"position_y":   2,
+				"player_owner": playerIds[0],
+			},
+			{
+				"name":         "end_turn",
+				"player_owner": playerIds[0],
+				"end_time":     int64(1650000000000),
+			},
+		},
+	}
+}
+
+func insertTestGame(t *testing.T, repo Repository, playerIds []string, winner string, startTime int64) string {
+	id, err := repo.InsertGame(newTestDefenition(playerIds, winner, startTime))
+	if err != nil {
+		t.Fatalf("Failed to insert game: %v", err)
+	}
+	return id
+}